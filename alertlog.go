@@ -2,13 +2,13 @@ package main
 
 import (
 	"bufio"
+	"context"
 	"os"
 	"regexp"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
-
-	"github.com/prometheus/common/log"
 )
 
 type Client struct {
@@ -36,12 +36,14 @@ var (
 	Errors    []oraerr
 	oralayout = "Mon Jan 02 15:04:05 2006"
 	lastlog   Lastlogs
+	// serializes the alertlog scans, which share the access file and Errors
+	alertlogMu sync.Mutex
 )
 
 // Get individual ScrapeTime per Prometheus instance for alertlog
-func (e *Exporter) GetLastScrapeTime(conf int) time.Time {
+func (e *Exporter) GetLastScrapeTime(config *Config) time.Time {
 	for i, _ := range lastlog.Cfgs {
-		if lastlog.Cfgs[i].Instance == config.Cfgs[conf].Instance {
+		if lastlog.Cfgs[i].Instance == config.Instance {
 			for n, _ := range lastlog.Cfgs[i].Clients {
 				if lastlog.Cfgs[i].Clients[n].Ip == e.lastIp {
 					t, _ := time.Parse("2006-01-02 15:04:05 -0700 MST", string(lastlog.Cfgs[i].Clients[n].Date))
@@ -54,11 +56,11 @@ func (e *Exporter) GetLastScrapeTime(conf int) time.Time {
 }
 
 // Set individual ScrapeTime per Prometheus instance for alertlog
-func (e *Exporter) SetLastScrapeTime(conf int, t time.Time) {
+func (e *Exporter) SetLastScrapeTime(config *Config, t time.Time) {
 	var indInst int = -1
 	var indIp int = -1
 	for i, _ := range lastlog.Cfgs {
-		if lastlog.Cfgs[i].Instance == config.Cfgs[conf].Instance {
+		if lastlog.Cfgs[i].Instance == config.Instance {
 			indInst = i
 			for n, _ := range lastlog.Cfgs[i].Clients {
 				if lastlog.Cfgs[i].Clients[n].Ip == e.lastIp {
@@ -69,7 +71,7 @@ func (e *Exporter) SetLastScrapeTime(conf int, t time.Time) {
 	}
 	if indInst == -1 {
 		cln := Client{Ip: e.lastIp, Date: t.Format("2006-01-02 15:04:05 -0700 MST")}
-		lastlog.Cfgs = append(lastlog.Cfgs, Lastlog{Instance: config.Cfgs[conf].Instance,
+		lastlog.Cfgs = append(lastlog.Cfgs, Lastlog{Instance: config.Instance,
 			Clients: []Client{cln}})
 	} else {
 		if indIp == -1 {
@@ -81,7 +83,7 @@ func (e *Exporter) SetLastScrapeTime(conf int, t time.Time) {
 	}
 }
 
-func addError(conf int, ora string, text string) {
+func addError(config *Config, ora string, text string) {
 	var found bool = false
 	for i, _ := range Errors {
 		if Errors[i].ora == ora {
@@ -91,7 +93,7 @@ func addError(conf int, ora string, text string) {
 	}
 	if !found {
 		ignore := "0"
-		for _, e := range config.Cfgs[conf].Alertlog[0].Ignoreora {
+		for _, e := range config.Alertlog[0].Ignoreora {
 			if e == ora {
 				ignore = "1"
 			}
@@ -109,56 +111,56 @@ func addError(conf int, ora string, text string) {
 	}
 }
 
-func (e *Exporter) ScrapeAlertlog() {
+func (e *Exporter) ScrapeAlertlog(ctx context.Context, config *Config) error {
+	if len(config.Alertlog) == 0 {
+		return nil
+	}
 	loc := time.Now().Location()
 	re := regexp.MustCompile(`O(RA|GG)-[0-9]+`)
 
+	alertlogMu.Lock()
+	defer alertlogMu.Unlock()
+
 	ReadAccess()
-	for conf, _ := range config.Cfgs {
-		if len(config.Cfgs[conf].Alertlog) > 0 {
+	defer WriteAccess()
 
-			var lastTime time.Time
-			Errors = nil
-			lastScrapeTime := e.GetLastScrapeTime(conf).Add(time.Second)
+	var lastTime time.Time
+	Errors = nil
+	lastScrapeTime := e.GetLastScrapeTime(config).Add(time.Second)
 
-			info, err := os.Stat(config.Cfgs[conf].Alertlog[0].File)
-			file, err := os.Open(config.Cfgs[conf].Alertlog[0].File)
-			if err != nil {
-				log.Infoln(err)
-			} else {
-				scanner := bufio.NewScanner(file)
-				for scanner.Scan() {
-					t, err := time.ParseInLocation(oralayout, scanner.Text(), loc)
-					if err == nil {
-						lastTime = t
-					} else {
-						if lastTime.After(lastScrapeTime) {
-							if re.MatchString(scanner.Text()) {
-								ora := re.FindString(scanner.Text())
-								addError(conf, ora, scanner.Text())
-							}
-						}
-					}
-				}
-				e.SetLastScrapeTime(conf, lastTime)
-				for i, _ := range Errors {
-					e.alertlog.WithLabelValues(config.Cfgs[conf].Database,
-						config.Cfgs[conf].Instance,
-						Errors[i].ora,
-						strings.ToValidUTF8(Errors[i].text,""),
-						Errors[i].ignore).Set(float64(Errors[i].count))
-					WriteLog(config.Cfgs[conf].Instance + " " + e.lastIp +
-						" (" + Errors[i].ignore + "/" + strconv.Itoa(Errors[i].count) + "): " +
-						Errors[i].ora + " - " + Errors[i].text)
-				}
-				e.alertdate.WithLabelValues(config.Cfgs[conf].Database,
-					config.Cfgs[conf].Instance).Set(float64(info.ModTime().Unix()))
-			}
+	info, err := os.Stat(config.Alertlog[0].File)
+	file, err := os.Open(config.Alertlog[0].File)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
 
-			if file != nil {
-				file.Close()
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		t, err := time.ParseInLocation(oralayout, scanner.Text(), loc)
+		if err == nil {
+			lastTime = t
+		} else {
+			if lastTime.After(lastScrapeTime) {
+				if re.MatchString(scanner.Text()) {
+					ora := re.FindString(scanner.Text())
+					addError(config, ora, scanner.Text())
+				}
 			}
 		}
 	}
-	WriteAccess()
+	e.SetLastScrapeTime(config, lastTime)
+	for i, _ := range Errors {
+		e.alertlog.WithLabelValues(config.Database,
+			config.Instance,
+			Errors[i].ora,
+			strings.ToValidUTF8(Errors[i].text, ""),
+			Errors[i].ignore).Set(float64(Errors[i].count))
+		WriteLog(config.Instance + " " + e.lastIp +
+			" (" + Errors[i].ignore + "/" + strconv.Itoa(Errors[i].count) + "): " +
+			Errors[i].ora + " - " + Errors[i].text)
+	}
+	e.alertdate.WithLabelValues(config.Database,
+		config.Instance).Set(float64(info.ModTime().Unix()))
+	return scanner.Err()
 }
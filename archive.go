@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"time"
+)
+
+var archiveWindow = flag.Duration("collector.archive.window", time.Hour, "Time range of the archived logs counted by the archive collector.")
+
+// ScrapeArchive collects the archived redo of the last window from
+// v$archived_log and the status and space of the archive destinations.
+func (e *Exporter) ScrapeArchive(ctx context.Context, config *Config) error {
+	db := config.db
+
+	// a log archived to several destinations is counted once
+	rows, err := db.QueryContext(ctx, `select thread#, count(*), NVL(sum(blocks*block_size),0)
+                                 from (select distinct thread#, sequence#, resetlogs_change#, blocks, block_size
+                                       from v$archived_log
+                                       where completion_time > sysdate - :1/86400 and standby_dest = 'NO')
+                                 group by thread#`, archiveWindow.Seconds())
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var thread string
+		var files float64
+		var bytes float64
+		if err := rows.Scan(&thread, &files, &bytes); err != nil {
+			return err
+		}
+		e.archivedFiles.WithLabelValues(config.Database, config.Instance, thread).Set(files)
+		e.archivedBytes.WithLabelValues(config.Database, config.Instance, thread).Set(bytes)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	// the space of a destination in the FRA is the one of the FRA, v$recovery_file_dest has a single row
+	rows, err = db.QueryContext(ctx, `select d.dest_name, NVL(d.destination,' '), d.status,
+                                 NVL(decode(d.destination, 'USE_DB_RECOVERY_FILE_DEST', r.space_limit, d.quota_size),0),
+                                 NVL(decode(d.destination, 'USE_DB_RECOVERY_FILE_DEST', r.space_used - r.space_reclaimable, d.quota_used),0)
+                                 from v$archive_dest d, v$recovery_file_dest r
+                                 where d.status != 'INACTIVE'`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name string
+		var destination string
+		var status string
+		var limit float64
+		var used float64
+		if err := rows.Scan(&name, &destination, &status, &limit, &used); err != nil {
+			return err
+		}
+		e.archiveDest.WithLabelValues(config.Database, config.Instance, name, destination, status).Set(1)
+		// remote destinations and local ones without quota have no limit
+		if limit > 0 {
+			e.archiveDestSpace.WithLabelValues(config.Database, config.Instance, name, "limit").Set(limit)
+			e.archiveDestSpace.WithLabelValues(config.Database, config.Instance, name, "used").Set(used)
+			e.archiveDestSpace.WithLabelValues(config.Database, config.Instance, name, "free").Set(limit - used)
+		}
+	}
+	return rows.Err()
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+)
+
+// ScrapeAsm collects the diskgroups with the space usable under their
+// redundancy, the state and errors of the disks and running rebalances.
+func (e *Exporter) ScrapeAsm(ctx context.Context, config *Config) error {
+	db := config.db
+
+	// the _stat views return the cached values and don't discover the disks
+	rows, err := db.QueryContext(ctx, `select name, type, state, total_mb, free_mb, usable_file_mb, required_mirror_free_mb, offline_disks
+                                 from v$asm_diskgroup_stat`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name string
+		var redundancy string
+		var state string
+		var total, free, usable, mirror, offline float64
+		if err := rows.Scan(&name, &redundancy, &state, &total, &free, &usable, &mirror, &offline); err != nil {
+			return err
+		}
+		e.asmDiskgroupState.WithLabelValues(config.Database, config.Instance, name, redundancy, state).Set(1)
+		e.asmDiskgroupSpace.WithLabelValues(config.Database, config.Instance, name, redundancy, "total").Set(total * 1024 * 1024)
+		e.asmDiskgroupSpace.WithLabelValues(config.Database, config.Instance, name, redundancy, "free").Set(free * 1024 * 1024)
+		e.asmDiskgroupSpace.WithLabelValues(config.Database, config.Instance, name, redundancy, "usable_file").Set(usable * 1024 * 1024)
+		e.asmDiskgroupSpace.WithLabelValues(config.Database, config.Instance, name, redundancy, "required_mirror_free").Set(mirror * 1024 * 1024)
+		e.asmOfflineDisks.WithLabelValues(config.Database, config.Instance, name, redundancy).Set(offline)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	// disks which are not part of a diskgroup have group_number 0
+	rows, err = db.QueryContext(ctx, `select g.name, d.name, d.mount_status, d.mode_status, d.state, d.read_errs, d.write_errs
+                                 from v$asm_disk_stat d JOIN v$asm_diskgroup_stat g ON g.group_number = d.group_number
+                                 where d.group_number > 0`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var group, disk string
+		var mount, mode, state string
+		var readErrs, writeErrs float64
+		if err := rows.Scan(&group, &disk, &mount, &mode, &state, &readErrs, &writeErrs); err != nil {
+			return err
+		}
+		e.asmDiskMountStatus.WithLabelValues(config.Database, config.Instance, group, disk, mount).Set(1)
+		e.asmDiskModeStatus.WithLabelValues(config.Database, config.Instance, group, disk, mode).Set(1)
+		e.asmDiskState.WithLabelValues(config.Database, config.Instance, group, disk, state).Set(1)
+		e.asmDiskErrors.WithLabelValues(config.Database, config.Instance, group, disk, "read").Set(readErrs)
+		e.asmDiskErrors.WithLabelValues(config.Database, config.Instance, group, disk, "write").Set(writeErrs)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	rows, err = db.QueryContext(ctx, `select g.name, o.operation, o.pass, o.state, NVL(o.power,0), NVL(o.sofar,0), NVL(o.est_work,0), NVL(o.est_minutes,0)
+                                 from v$asm_operation o JOIN v$asm_diskgroup_stat g ON g.group_number = o.group_number`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var group, operation, pass, state string
+		var power, sofar, work, minutes float64
+		if err := rows.Scan(&group, &operation, &pass, &state, &power, &sofar, &work, &minutes); err != nil {
+			return err
+		}
+		// est_work is 0 while the work is estimated
+		progress := 0.0
+		if work > 0 {
+			progress = sofar / work
+		}
+		e.asmOperationProgress.WithLabelValues(config.Database, config.Instance, group, operation, pass, state).Set(progress)
+		e.asmOperationRemaining.WithLabelValues(config.Database, config.Instance, group, operation, pass, state).Set(minutes * 60)
+		e.asmOperationPower.WithLabelValues(config.Database, config.Instance, group, operation, pass, state).Set(power)
+	}
+	return rows.Err()
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// queryResult is the outcome of the last background run of a custom query
+// with an interval.
+type queryResult struct {
+	// samples of the last successful run
+	samples []customSample
+	updated time.Time
+	// last run
+	duration time.Duration
+	err      error
+	done     bool
+}
+
+// queryJob runs a custom query with an interval for one connection in the
+// background, like the collectors of schedule.go, so the query isn't bound to
+// the scrape deadline.
+type queryJob struct {
+	mu     sync.Mutex
+	result queryResult
+	// stops the runs, see stopQueryJobs
+	cancel context.CancelFunc
+}
+
+var (
+	queryJobs   = map[string]*queryJob{}
+	queryJobsMu sync.Mutex
+)
+
+func queryJobKey(config *Config, query Query) string {
+	return config.poolKey() + "/" + query.Name
+}
+
+// cachedQueryResult returns the cached result of the query for the config,
+// the background runs are started on first use.
+func cachedQueryResult(config *Config, query Query) queryResult {
+	key := queryJobKey(config, query)
+
+	queryJobsMu.Lock()
+	job, ok := queryJobs[key]
+	if !ok {
+		ctx, cancel := context.WithCancel(context.Background())
+		job = &queryJob{cancel: cancel}
+		queryJobs[key] = job
+		// own copy, the db of the exporter's config is reassigned on every scrape
+		go job.loop(ctx, *config, query)
+	}
+	queryJobsMu.Unlock()
+
+	job.mu.Lock()
+	defer job.mu.Unlock()
+	return job.result
+}
+
+func (job *queryJob) loop(ctx context.Context, config Config, query Query) {
+	for {
+		job.run(ctx, &config, query)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(query.Interval):
+		}
+	}
+}
+
+// stopQueryJobs stops all background runs of queries and drops their results,
+// they are started again by the next scrape.
+func stopQueryJobs() {
+	queryJobsMu.Lock()
+	defer queryJobsMu.Unlock()
+
+	for _, job := range queryJobs {
+		job.cancel()
+	}
+	queryJobs = map[string]*queryJob{}
+}
+
+// run runs the query and caches its samples. A run may take up to the
+// timeout of the query, or the interval without one. A failed run keeps the
+// previous samples and is retried after the interval.
+func (job *queryJob) run(ctx context.Context, config *Config, query Query) {
+	timeout := query.Interval
+	if query.Timeout > 0 {
+		timeout = query.Timeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	begun := time.Now()
+	samples, err := func() ([]customSample, error) {
+		db, err := openPool(config)
+		if err != nil {
+			return nil, err
+		}
+		if err := checkPool(ctx, db); err != nil {
+			return nil, config.redact(err)
+		}
+		config.db = db
+		return NewExporter().ScrapeCustomQuery(ctx, config, query, *pNoRownum)
+	}()
+
+	logger := dbLogger(config).With("query", query.Name)
+	if err != nil {
+		logger.Errorln("background query failed: ", err)
+	} else {
+		logger.Debugln("background query finished in ", time.Since(begun))
+	}
+
+	job.mu.Lock()
+	defer job.mu.Unlock()
+	job.result.done = true
+	job.result.duration = time.Since(begun)
+	job.result.err = err
+	if err == nil {
+		job.result.samples = samples
+		job.result.updated = time.Now()
+	}
+}
+
+// collectCachedQueries adds the cached results of the custom queries with an
+// interval to the samples of the scrape and sets their age and the outcome of
+// their last run. Results older than the ttl are left out.
+func (e *Exporter) collectCachedQueries(custom *customSamples) {
+	e.queryAge.Reset()
+
+	for _, config := range e.configs {
+		for _, query := range config.Queries {
+			if query.Interval <= 0 {
+				continue
+			}
+			result := cachedQueryResult(config, query)
+			if !result.done {
+				continue
+			}
+			name := "custom_" + query.Name
+			if result.err != nil {
+				e.collectorUp.WithLabelValues(name, config.Database, config.Instance).Set(0)
+			} else {
+				e.collectorUp.WithLabelValues(name, config.Database, config.Instance).Set(1)
+			}
+			e.collectorTime.WithLabelValues(name, config.Database, config.Instance).Set(result.duration.Seconds())
+			// no successful run yet
+			if result.updated.IsZero() {
+				continue
+			}
+			e.lastSuccess.WithLabelValues(name, config.Database, config.Instance).Set(float64(result.updated.Unix()))
+			age := time.Since(result.updated)
+			if query.TTL > 0 && age > query.TTL {
+				dbLogger(config).With("query", query.Name).Debugln("cached result is older than ttl ", query.TTL)
+				continue
+			}
+			e.queryAge.WithLabelValues(query.Name, config.Database, config.Instance).Set(age.Seconds())
+			custom.add(result.samples)
+		}
+	}
+}
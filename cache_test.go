@@ -0,0 +1,131 @@
+package main
+
+import (
+	"database/sql/driver"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// waitQueryJob waits until the background query has finished a run.
+func waitQueryJob(t *testing.T, config *Config, query Query) queryResult {
+	for begun := time.Now(); time.Since(begun) < 5*time.Second; time.Sleep(5 * time.Millisecond) {
+		if result := cachedQueryResult(config, query); result.done {
+			return result
+		}
+	}
+	t.Fatalf("query %s did not finish", query.Name)
+	return queryResult{}
+}
+
+func TestCachedQuerySlowerThanScrape(t *testing.T) {
+	defer stopQueryJobs()
+	wait := make(chan struct{})
+	db := &fakeDB{results: upResults(
+		fakeResult{match: "from slow", columns: []string{"VALUE"}, rows: [][]driver.Value{{int64(1)}}, wait: wait},
+	)}
+	config := addFakeDB("cache_slow", db)
+	defer dropPool(config)
+	query := Query{Name: "slow", Help: "slow", Sql: "select value from slow", Interval: time.Hour, Metrics: []QueryMetric{{Column: "value"}}}
+	config.Queries = []Query{query}
+
+	e := NewExporter()
+	e.configs = []*Config{config}
+	e.collect = map[string]bool{}
+	e.timeout = 100 * time.Millisecond
+	begun := time.Now()
+	testutil.CollectAndCount(e)
+	if d := time.Since(begun); d > time.Second {
+		t.Fatalf("scrape waited %v for the background query", d)
+	}
+
+	// the query takes longer than the scrape, it still finishes in the background
+	close(wait)
+	if result := waitQueryJob(t, config, query); result.err != nil {
+		t.Fatalf("background query failed: %v", result.err)
+	}
+	samples := newCustomSamples()
+	e.collectCachedQueries(samples)
+	if got := testutil.CollectAndCount(collectorFunc(samples.collect)); got != 1 {
+		t.Errorf("collected %d samples of the slow query, want 1", got)
+	}
+	if n := db.count("from slow"); n != 1 {
+		t.Errorf("slow query ran %d times within its interval, want 1", n)
+	}
+}
+
+func TestCachedQueryFailureWaitsForInterval(t *testing.T) {
+	defer stopQueryJobs()
+	db := &fakeDB{results: upResults(fakeResult{match: "expensive", err: errors.New("ORA-01555: snapshot too old")})}
+	config := addFakeDB("cache_failing", db)
+	defer dropPool(config)
+	query := Query{Name: "expensive", Sql: "select * from expensive", Interval: time.Hour, Metrics: []QueryMetric{{Column: "value"}}}
+	config.Queries = []Query{query}
+
+	e := NewExporter()
+	e.configs = []*Config{config}
+	waitQueryJob(t, config, query)
+	for i := 0; i < 3; i++ {
+		e.collectCachedQueries(newCustomSamples())
+	}
+	if n := db.count("expensive"); n != 1 {
+		t.Errorf("failing query ran %d times within its interval, want 1", n)
+	}
+	if got := testutil.ToFloat64(e.collectorUp.WithLabelValues("custom_expensive", config.Database, config.Instance)); got != 0 {
+		t.Errorf("collector_success = %v, want 0", got)
+	}
+}
+
+func TestCachedQueryTTL(t *testing.T) {
+	defer stopQueryJobs()
+	desc := prometheus.NewDesc("oracledb_custom_ttl", "ttl", nil, nil)
+	tests := []struct {
+		name    string
+		age     time.Duration
+		ttl     time.Duration
+		samples int
+	}{
+		{"no ttl", 12 * time.Hour, 0, 1},
+		{"younger than ttl", time.Minute, time.Hour, 1},
+		{"older than ttl", 2 * time.Hour, time.Hour, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &Config{Database: "cache_ttl", Instance: "cache_ttl", Dsn: "cache_ttl"}
+			query := Query{Name: "ttl", Interval: 24 * time.Hour, TTL: tt.ttl}
+			config.Queries = []Query{query}
+			queryJobsMu.Lock()
+			queryJobs[queryJobKey(config, query)] = &queryJob{cancel: func() {}, result: queryResult{
+				samples: []customSample{{key: "ttl", metric: prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, 1)}},
+				updated: time.Now().Add(-tt.age),
+				done:    true,
+			}}
+			queryJobsMu.Unlock()
+
+			e := NewExporter()
+			e.configs = []*Config{config}
+			custom := newCustomSamples()
+			e.collectCachedQueries(custom)
+			if got := testutil.CollectAndCount(collectorFunc(custom.collect)); got != tt.samples {
+				t.Errorf("collected %d samples, want %d", got, tt.samples)
+			}
+			if got := testutil.CollectAndCount(e.queryAge); got != tt.samples {
+				t.Errorf("collected %d ages, want %d", got, tt.samples)
+			}
+		})
+	}
+}
+
+// collectorFunc turns a collect function into a prometheus.Collector.
+type collectorFunc func(ch chan<- prometheus.Metric)
+
+func (f collectorFunc) Describe(ch chan<- *prometheus.Desc) {
+	prometheus.DescribeByCollect(f, ch)
+}
+
+func (f collectorFunc) Collect(ch chan<- prometheus.Metric) {
+	f(ch)
+}
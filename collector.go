@@ -0,0 +1,302 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// metricVec is a metric vector filled by a collector.
+type metricVec interface {
+	prometheus.Collector
+	Reset()
+}
+
+// counterVec is a vector of counters kept by the database, like the
+// statistics of v$sysstat. Unlike a prometheus.CounterVec their value is set
+// to the one read in the scrape.
+type counterVec struct {
+	desc   *prometheus.Desc
+	mu     sync.Mutex
+	values map[string]counterValue
+}
+
+type counterValue struct {
+	labelValues []string
+	value       float64
+}
+
+// counter sets the value of the counter with the label values.
+type counter struct {
+	vec         *counterVec
+	labelValues []string
+}
+
+func newCounterVec(opts prometheus.CounterOpts, labels []string) *counterVec {
+	return &counterVec{
+		desc:   prometheus.NewDesc(prometheus.BuildFQName(opts.Namespace, opts.Subsystem, opts.Name), opts.Help, labels, opts.ConstLabels),
+		values: map[string]counterValue{},
+	}
+}
+
+func (v *counterVec) WithLabelValues(labelValues ...string) counter {
+	return counter{vec: v, labelValues: labelValues}
+}
+
+func (c counter) Set(value float64) {
+	c.vec.mu.Lock()
+	defer c.vec.mu.Unlock()
+	c.vec.values[strings.Join(c.labelValues, "\xff")] = counterValue{labelValues: c.labelValues, value: value}
+}
+
+func (v *counterVec) Describe(ch chan<- *prometheus.Desc) {
+	ch <- v.desc
+}
+
+func (v *counterVec) Collect(ch chan<- prometheus.Metric) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	for _, c := range v.values {
+		metric, err := prometheus.NewConstMetric(v.desc, prometheus.CounterValue, c.value, c.labelValues...)
+		if err != nil {
+			metric = prometheus.NewInvalidMetric(v.desc, err)
+		}
+		ch <- metric
+	}
+}
+
+func (v *counterVec) Reset() {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.values = map[string]counterValue{}
+}
+
+// collector is a built-in collector, it can be switched on and off by flags,
+// per connection and per scrape request.
+type collector struct {
+	name           string
+	help           string
+	defaultEnabled bool
+	// local collectors read files on the exporter host and run without a DB connection
+	local bool
+	// the collector reads the data dictionary and is skipped if the instance is only started or mounted
+	needsOpen bool
+	// the collector reads v$database or views of the database's files and is
+	// skipped on ASM instances and instances which are only started
+	needsMount bool
+	// expensive collectors run in the background on this interval, see schedule.go
+	interval time.Duration
+	scrape   func(e *Exporter, ctx context.Context, config *Config) error
+	metrics  func(e *Exporter) []metricVec
+
+	enableFlag   *bool
+	disableFlag  *bool
+	intervalFlag *time.Duration
+	enabled      bool
+}
+
+// collectors lists all built-in collectors in the order they are scraped.
+var collectors = []*collector{
+	{name: "recovery", help: "percentage usage of FRA from v$recovery_file_dest (CAN TAKE VERY LONG)", interval: time.Hour, needsMount: true,
+		scrape:  (*Exporter).ScrapeRecovery,
+		metrics: func(e *Exporter) []metricVec { return []metricVec{e.recovery} }},
+	{name: "uptime", help: "instance uptime", defaultEnabled: true,
+		scrape:  (*Exporter).ScrapeUptime,
+		metrics: func(e *Exporter) []metricVec { return []metricVec{e.uptime} }},
+	{name: "session", help: "sessions from v$session", defaultEnabled: true,
+		scrape:  (*Exporter).ScrapeSession,
+		metrics: func(e *Exporter) []metricVec { return []metricVec{e.session} }},
+	{name: "sysstat", help: "statistics from v$sysstat", defaultEnabled: true,
+		scrape:  (*Exporter).ScrapeSysstat,
+		metrics: func(e *Exporter) []metricVec { return []metricVec{e.sysstat} }},
+	{name: "waitclass", help: "wait classes from v$waitclassmetric", defaultEnabled: true,
+		scrape:  (*Exporter).ScrapeWaitclass,
+		metrics: func(e *Exporter) []metricVec { return []metricVec{e.waitclass} }},
+	{name: "waitevent", help: "top wait events from v$system_event", defaultEnabled: true,
+		scrape: (*Exporter).ScrapeWaitevent,
+		metrics: func(e *Exporter) []metricVec {
+			return []metricVec{e.waitEventWaits, e.waitEventTimeouts, e.waitEventTime}
+		}},
+	{name: "sysmetric", help: "metrics from v$sysmetric", defaultEnabled: true,
+		scrape:  (*Exporter).ScrapeSysmetric,
+		metrics: func(e *Exporter) []metricVec { return []metricVec{e.sysmetric} }},
+	{name: "tablespace", help: "tablespace total/free", defaultEnabled: true, needsOpen: true,
+		scrape:  (*Exporter).ScrapeTablespace,
+		metrics: func(e *Exporter) []metricVec { return []metricVec{e.tablespace} }},
+	{name: "interconnect", help: "RAC interconnect transfers from v$sysstat", defaultEnabled: true,
+		scrape:  (*Exporter).ScrapeInterconnect,
+		metrics: func(e *Exporter) []metricVec { return []metricVec{e.interconnect} }},
+	{name: "redo", help: "redo log switches and online logs from v$log_history and v$log", defaultEnabled: true, needsMount: true,
+		scrape: (*Exporter).ScrapeRedo,
+		metrics: func(e *Exporter) []metricVec {
+			return []metricVec{e.redo, e.redoSwitches, e.redoLogStatus, e.redoLogArchived}
+		}},
+	{name: "archive", help: "archived logs and archive destinations from v$archived_log and v$archive_dest", defaultEnabled: true, needsMount: true,
+		scrape: (*Exporter).ScrapeArchive,
+		metrics: func(e *Exporter) []metricVec {
+			return []metricVec{e.archivedFiles, e.archivedBytes, e.archiveDest, e.archiveDestSpace}
+		}},
+	{name: "cache", help: "cache hit ratios from v$sysmetric", defaultEnabled: true,
+		scrape:  (*Exporter).ScrapeCache,
+		metrics: func(e *Exporter) []metricVec { return []metricVec{e.cache} }},
+	{name: "alertlog", help: "errors parsed from the alert.log", defaultEnabled: true, local: true,
+		scrape:  (*Exporter).ScrapeAlertlog,
+		metrics: func(e *Exporter) []metricVec { return []metricVec{e.alertlog, e.alertdate} }},
+	{name: "services", help: "active services from v$active_services", defaultEnabled: true,
+		scrape:  (*Exporter).ScrapeServices,
+		metrics: func(e *Exporter) []metricVec { return []metricVec{e.services} }},
+	{name: "parameter", help: "parameters from v$parameter", defaultEnabled: true,
+		scrape:  (*Exporter).ScrapeParameter,
+		metrics: func(e *Exporter) []metricVec { return []metricVec{e.parameter, e.parameterInfo} }},
+	{name: "resource", help: "utilization and limits of processes, sessions etc. from v$resource_limit", defaultEnabled: true,
+		scrape: (*Exporter).ScrapeResourceLimit,
+		metrics: func(e *Exporter) []metricVec {
+			return []metricVec{e.resourceCurrent, e.resourceMax, e.resourceLimit}
+		}},
+	{name: "asmspace", help: "ASM diskgroup space from v$asm_diskgroup", defaultEnabled: true,
+		scrape:  (*Exporter).ScrapeAsmspace,
+		metrics: func(e *Exporter) []metricVec { return []metricVec{e.asmspace} }},
+	{name: "asm", help: "ASM diskgroups with redundancy, disk state and errors, rebalance from v$asm_diskgroup_stat, v$asm_disk_stat and v$asm_operation", defaultEnabled: true,
+		scrape: (*Exporter).ScrapeAsm,
+		metrics: func(e *Exporter) []metricVec {
+			return []metricVec{e.asmDiskgroupState, e.asmDiskgroupSpace, e.asmOfflineDisks, e.asmDiskMountStatus, e.asmDiskModeStatus,
+				e.asmDiskState, e.asmDiskErrors, e.asmOperationProgress, e.asmOperationRemaining, e.asmOperationPower}
+		}},
+	{name: "pdb", help: "state and resource usage of the PDBs from v$pdbs and v$rsrcpdbmetric", defaultEnabled: true,
+		scrape: (*Exporter).ScrapePdb,
+		metrics: func(e *Exporter) []metricVec {
+			return []metricVec{e.pdbOpenMode, e.pdbRestricted, e.pdbSize, e.pdbRecovery, e.pdbResource}
+		}},
+	{name: "dataguard", help: "role, standby lag and archive destination errors from v$database, v$dataguard_stats and v$archive_dest_status", needsMount: true,
+		scrape: (*Exporter).ScrapeDataguard,
+		metrics: func(e *Exporter) []metricVec {
+			return []metricVec{e.dataguardRole, e.dataguardProtection, e.dataguardSwitchover, e.dataguardLag,
+				e.dataguardApplyFinish, e.archiveDestStatus, e.archiveDestError}
+		}},
+	{name: "tablerows", help: "rows of all tables (CAN TAKE VERY LONG)", interval: time.Hour, needsOpen: true,
+		scrape:  (*Exporter).ScrapeTablerows,
+		metrics: func(e *Exporter) []metricVec { return []metricVec{e.tablerows} }},
+	{name: "tablebytes", help: "size of all tables (CAN TAKE VERY LONG)", interval: time.Hour, needsOpen: true,
+		scrape:  (*Exporter).ScrapeTablebytes,
+		metrics: func(e *Exporter) []metricVec { return []metricVec{e.tablebytes} }},
+	{name: "indexbytes", help: "size of all indexes per table (CAN TAKE VERY LONG)", interval: time.Hour, needsOpen: true,
+		scrape:  (*Exporter).ScrapeIndexbytes,
+		metrics: func(e *Exporter) []metricVec { return []metricVec{e.indexbytes} }},
+	{name: "lobbytes", help: "size of all lobs per table (CAN TAKE VERY LONG)", interval: time.Hour, needsOpen: true,
+		scrape:  (*Exporter).ScrapeLobbytes,
+		metrics: func(e *Exporter) []metricVec { return []metricVec{e.lobbytes} }},
+}
+
+// legacyCollectorFlags are the old flags (and URL parameters) to enable a collector.
+var legacyCollectorFlags = map[string]*bool{
+	"tablerows":  pTabRows,
+	"tablebytes": pTabBytes,
+	"indexbytes": pIndBytes,
+	"lobbytes":   pLobBytes,
+	"recovery":   pRecovery,
+}
+
+func init() {
+	for _, c := range collectors {
+		c.enableFlag = flag.Bool("collector."+c.name, c.defaultEnabled, "Enable the "+c.name+" collector: "+c.help+".")
+		c.disableFlag = flag.Bool("no-collector."+c.name, false, "Disable the "+c.name+" collector.")
+		c.intervalFlag = flag.Duration("collector."+c.name+".interval", c.interval, "Run the "+c.name+" collector in the background on this interval and serve its cached result, 0 runs it on every scrape.")
+	}
+}
+
+// initCollectors decides which collectors are enabled by the command line.
+// Must be called after flag.Parse.
+func initCollectors() {
+	set := map[string]bool{}
+	flag.Visit(func(f *flag.Flag) { set[f.Name] = true })
+
+	for _, c := range collectors {
+		c.enabled = *c.enableFlag
+		if !set["collector."+c.name] {
+			// -defaultmetrics=false and -tablerows etc. only apply without an explicit -collector.<name>
+			if c.defaultEnabled && !*pMetrics {
+				c.enabled = false
+			}
+			if legacy, ok := legacyCollectorFlags[c.name]; ok && *legacy {
+				c.enabled = true
+			}
+		}
+		if *c.disableFlag {
+			c.enabled = false
+		}
+	}
+}
+
+// background tells whether the collector runs on its own schedule instead of on every scrape.
+func (c *collector) background() bool {
+	return *c.intervalFlag > 0
+}
+
+// lookupCollector returns the collector with the given name or nil.
+func lookupCollector(name string) *collector {
+	for _, c := range collectors {
+		if c.name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+// collectorNames returns the sorted names of all collectors.
+func collectorNames() []string {
+	names := []string{}
+	for _, c := range collectors {
+		names = append(names, c.name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// parseCollect returns the set of collectors requested by collect[] URL parameters,
+// nil if all enabled collectors should run.
+func parseCollect(query map[string][]string) (map[string]bool, error) {
+	var collect map[string]bool
+	for _, name := range query["collect[]"] {
+		if lookupCollector(name) == nil {
+			return nil, fmt.Errorf("unknown collector %q, available: %v", name, collectorNames())
+		}
+		if collect == nil {
+			collect = map[string]bool{}
+		}
+		collect[name] = true
+	}
+
+	// deprecated ?tablerows=true etc. add a collector to the enabled ones
+	for name := range legacyCollectorFlags {
+		if len(query[name]) > 0 && query[name][0] == "true" {
+			if collect == nil {
+				collect = map[string]bool{}
+				for _, c := range collectors {
+					collect[c.name] = c.enabled
+				}
+			}
+			collect[name] = true
+		}
+	}
+	return collect, nil
+}
+
+// enabled tells whether the collector runs for the config in the current exporter.
+// collect[] in the URL overrides the flags, a connection can switch collectors
+// on and off in its collectors setting.
+func (e *Exporter) enabled(c *collector, config *Config) bool {
+	on, ok := config.Collectors[c.name]
+	if e.collect != nil {
+		return e.collect[c.name] && (!ok || on)
+	}
+	if ok {
+		return on
+	}
+	return c.enabled
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseCollect(t *testing.T) {
+	// the collectors enabled by the command line, the base of the legacy parameters
+	enabled := func(extra ...string) map[string]bool {
+		collect := map[string]bool{}
+		for _, c := range collectors {
+			collect[c.name] = c.enabled
+		}
+		for _, name := range extra {
+			collect[name] = true
+		}
+		return collect
+	}
+
+	tests := []struct {
+		name  string
+		query string
+		want  map[string]bool
+		// part of the error, empty if there is none
+		err string
+	}{
+		{name: "no parameters", query: ""},
+		{name: "unrelated parameters", query: "target=db&tablerows=false"},
+		{
+			name:  "collect[]",
+			query: "collect[]=session&collect[]=tablespace",
+			want:  map[string]bool{"session": true, "tablespace": true},
+		},
+		{
+			name:  "legacy parameter adds to the enabled collectors",
+			query: "tablerows=true",
+			want:  enabled("tablerows"),
+		},
+		{
+			name:  "legacy parameter adds to collect[]",
+			query: "collect[]=session&recovery=true",
+			want:  map[string]bool{"session": true, "recovery": true},
+		},
+		{
+			name:  "unknown collector",
+			query: "collect[]=session&collect[]=nosuch",
+			err:   `unknown collector "nosuch"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, err := url.ParseQuery(tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := parseCollect(query)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("got error %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+var envVar = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// expandEnv replaces ${VAR} in the strings of the decoded config or query
+// file by the environment variable, all variables must be set. The values
+// are inserted after decoding, so they can't change the structure of the
+// file, and they never show up in an error.
+func expandEnv(v interface{}) error {
+	var missing []string
+	expandValue(reflect.ValueOf(v), &missing)
+	if len(missing) > 0 {
+		return fmt.Errorf("environment variables not set: %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// expandValue expands the strings in the exported fields, slices and map
+// values of v.
+func expandValue(v reflect.Value, missing *[]string) {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if !v.IsNil() {
+			expandValue(v.Elem(), missing)
+		}
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if field := v.Field(i); field.CanSet() {
+				expandValue(field, missing)
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			expandValue(v.Index(i), missing)
+		}
+	case reflect.Map:
+		// map values can't be set in place
+		for _, key := range v.MapKeys() {
+			value := reflect.New(v.Type().Elem()).Elem()
+			value.Set(v.MapIndex(key))
+			expandValue(value, missing)
+			v.SetMapIndex(key, value)
+		}
+	case reflect.String:
+		v.SetString(envVar.ReplaceAllStringFunc(v.String(), func(m string) string {
+			name := envVar.FindStringSubmatch(m)[1]
+			value, ok := os.LookupEnv(name)
+			if !ok {
+				*missing = append(*missing, name)
+			}
+			return value
+		}))
+	}
+}
+
+// loadPassword reads the password_file of the connection into its password.
+func (c *Config) loadPassword() error {
+	if c.PasswordFile == "" {
+		return nil
+	}
+	content, err := ioutil.ReadFile(c.PasswordFile)
+	if err != nil {
+		return err
+	}
+	c.Password = strings.TrimRight(string(content), "\r\n")
+	return nil
+}
+
+// validateCredentials checks that the connection is set up either by the
+// connection string or by user, password and dsn, or by the dsn alone.
+func (c *Config) validateCredentials() []string {
+	var problems []string
+	if c.Connection == "" && c.User == "" && c.Dsn == "" {
+		problems = append(problems, "connection or dsn is missing")
+	}
+	if c.Connection != "" && (c.User != "" || c.Dsn != "") {
+		problems = append(problems, "connection can't be combined with user and dsn")
+	}
+	if c.User != "" && c.Dsn == "" {
+		problems = append(problems, "dsn is missing")
+	}
+	if c.User == "" && (c.Password != "" || c.PasswordFile != "") {
+		problems = append(problems, "a password needs a user, without user the wallet or OS authentication is used")
+	}
+	if c.Password != "" && c.PasswordFile != "" {
+		problems = append(problems, "password and password_file are exclusive")
+	}
+	if c.User != "" && c.Password == "" && c.PasswordFile == "" {
+		problems = append(problems, "password or password_file is missing")
+	}
+	return problems
+}
+
+// connectString returns the connect string for the driver, it contains the
+// password and must not be logged. A dsn without user connects with the
+// credentials of the wallet or the OS user.
+func (c *Config) connectString() string {
+	return c.dbDriver().connectString(c)
+}
+
+// credentials returns user, password and dsn of the connection, split from the
+// connection string if it is given as <user>/<pass>@<tnsname>.
+func (c *Config) credentials() (user, password, dsn string) {
+	if c.Connection == "" {
+		return c.User, c.Password, c.Dsn
+	}
+	i := strings.Index(c.Connection, "/")
+	j := strings.LastIndex(c.Connection, "@")
+	if i == -1 || j < i {
+		return "", "", c.Connection
+	}
+	return c.Connection[:i], c.Connection[i+1 : j], c.Connection[j+1:]
+}
+
+// password returns the password of the connection.
+func (c *Config) password() string {
+	_, password, _ := c.credentials()
+	return password
+}
+
+// safeConnection returns the connection without the password, e.g. for logs.
+func (c *Config) safeConnection() string {
+	if c.Connection == "" {
+		if c.User == "" {
+			return "/@" + c.Dsn
+		}
+		return c.User + "@" + c.Dsn
+	}
+	if password := c.password(); password != "" {
+		return strings.Replace(c.Connection, "/"+password+"@", "@", 1)
+	}
+	return c.Connection
+}
+
+// redact removes the password from errors of the driver.
+func (c *Config) redact(err error) error {
+	if err == nil {
+		return nil
+	}
+	if password := c.password(); password != "" && strings.Contains(err.Error(), password) {
+		return errors.New(strings.Replace(err.Error(), password, "***", -1))
+	}
+	return err
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestExpandEnv(t *testing.T) {
+	os.Setenv("EXPAND_TEST_PW", "pa ss #1")
+	os.Setenv("EXPAND_TEST_ANCHOR", "*secret")
+	os.Setenv("EXPAND_TEST_DB", "DEV")
+	os.Unsetenv("EXPAND_TEST_MISSING")
+	defer func() {
+		os.Unsetenv("EXPAND_TEST_PW")
+		os.Unsetenv("EXPAND_TEST_ANCHOR")
+		os.Unsetenv("EXPAND_TEST_DB")
+	}()
+
+	tests := []struct {
+		name     string
+		config   string
+		password string
+		database string
+		// part of the error, empty if there is none
+		err string
+	}{
+		{
+			name:     "plain value with spaces and a comment sign",
+			config:   "connections:\n- user: u\n  password: ${EXPAND_TEST_PW}\n  dsn: db\n  database: ${EXPAND_TEST_DB}\n",
+			password: "pa ss #1",
+			database: "DEV",
+		},
+		{
+			name:     "value which looks like an alias",
+			config:   "connections:\n- user: u\n  password: ${EXPAND_TEST_ANCHOR}\n  dsn: db\n",
+			password: "*secret",
+		},
+		{
+			name:     "variable within a string",
+			config:   "connections:\n- user: u\n  password: \"x-${EXPAND_TEST_PW}-x\"\n  dsn: db\n",
+			password: "x-pa ss #1-x",
+		},
+		{
+			name:   "variable which is not set",
+			config: "connections:\n- user: u\n  password: ${EXPAND_TEST_PW}\n  dsn: ${EXPAND_TEST_MISSING}\n",
+			err:    "environment variables not set: EXPAND_TEST_MISSING",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var conf Configs
+			_, err := decodeYAML([]byte(tt.config), &conf)
+			if err == nil {
+				err = expandEnv(&conf)
+			}
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("got error %v, want %q", err, tt.err)
+				}
+				if strings.Contains(err.Error(), "pa ss") {
+					t.Errorf("error %q contains the value of a variable", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := conf.Cfgs[0].Password; got != tt.password {
+				t.Errorf("password is %q, want %q", got, tt.password)
+			}
+			if got := conf.Cfgs[0].Database; got != tt.database {
+				t.Errorf("database is %q, want %q", got, tt.database)
+			}
+		})
+	}
+}
+
+func TestValidateCredentials(t *testing.T) {
+	tests := []struct {
+		name   string
+		config Config
+		want   []string
+	}{
+		{name: "connection string", config: Config{Connection: "u/p@db"}},
+		{name: "user, password and dsn", config: Config{User: "u", Password: "p", Dsn: "db"}},
+		{name: "dsn of a wallet", config: Config{Dsn: "db"}},
+		{name: "nothing", config: Config{}, want: []string{"connection or dsn is missing"}},
+		{name: "connection and dsn", config: Config{Connection: "u/p@db", Dsn: "db"}, want: []string{"connection can't be combined with user and dsn"}},
+		{name: "user without dsn", config: Config{User: "u", Password: "p"}, want: []string{"dsn is missing"}},
+		{name: "user without password", config: Config{User: "u", Dsn: "db"}, want: []string{"password or password_file is missing"}},
+		{name: "password without user", config: Config{Password: "p", Dsn: "db"}, want: []string{"a password needs a user, without user the wallet or OS authentication is used"}},
+		{name: "password and password_file", config: Config{User: "u", Password: "p", PasswordFile: "f", Dsn: "db"}, want: []string{"password and password_file are exclusive"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.config.validateCredentials()
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
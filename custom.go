@@ -0,0 +1,284 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+)
+
+// Metric types of custom query metrics.
+const (
+	typeGauge     = "gauge"
+	typeCounter   = "counter"
+	typeUntyped   = "untyped"
+	typeHistogram = "histogram"
+)
+
+// typed tells whether the metric defines its own name instead of being a
+// column of oracledb_custom_<query>.
+func (m QueryMetric) typed() bool {
+	return m.Name != ""
+}
+
+func (m QueryMetric) valueType() prometheus.ValueType {
+	switch m.Type {
+	case typeCounter:
+		return prometheus.CounterValue
+	case typeUntyped:
+		return prometheus.UntypedValue
+	}
+	return prometheus.GaugeValue
+}
+
+// customLabels returns the variable labels of a custom metric, the label
+// values are passed in the same order.
+func customLabels(query Query, m QueryMetric) []string {
+	labels := []string{}
+	for _, label := range query.Labels {
+		labels = append(labels, cleanName(label))
+	}
+	if !m.typed() {
+		labels = append(labels, "metric")
+	}
+	labels = append(labels, "database", "dbinstance")
+	if *pNoRownum == false {
+		labels = append(labels, "rownum")
+	}
+	return labels
+}
+
+// customDesc returns the descriptor of a metric of a self defined query.
+func customDesc(query Query, m QueryMetric) *prometheus.Desc {
+	help := m.Help
+	if help == "" || !m.typed() {
+		help = query.Help
+	}
+	return prometheus.NewDesc(customFQName(query, m), help, customLabels(query, m), nil)
+}
+
+// customFQName returns the name of the metric as it is exported.
+func customFQName(query Query, m QueryMetric) string {
+	if m.typed() {
+		return m.Name
+	}
+	return prometheus.BuildFQName(namespace, "", "custom_"+cleanName(query.Name))
+}
+
+// findColumn returns the index of the column, -1 if it is missing.
+func findColumn(cols []string, name string) int {
+	for i, col := range cols {
+		if cleanName(name) == cleanName(col) {
+			return i
+		}
+	}
+	return -1
+}
+
+// toFloat converts a numeric column value.
+func toFloat(v interface{}) (float64, bool) {
+	switch value := v.(type) {
+	case float64:
+		return value, true
+	case float32:
+		return float64(value), true
+	case int64:
+		return float64(value), true
+	case int:
+		return float64(value), true
+	case string:
+		f, err := strconv.ParseFloat(value, 64)
+		return f, err == nil
+	case []byte:
+		f, err := strconv.ParseFloat(string(value), 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// toLabel converts a column value to a label value.
+func toLabel(v interface{}) string {
+	if a, ok := v.(string); ok {
+		return a
+	}
+	if b, ok := toFloat(v); ok {
+		// if value is integer
+		if b == float64(int64(b)) {
+			return strconv.Itoa(int(b))
+		}
+		return strconv.FormatFloat(b, 'e', -1, 64)
+	}
+	return ""
+}
+
+// ScrapeCustomQuery collects metrics from a self defined query from configuration file.
+func (e *Exporter) ScrapeCustomQuery(ctx context.Context, config *Config, query Query, pNoRownum bool) ([]customSample, error) {
+	db := config.db
+	logger := dbLogger(config).With("query", query.Name)
+
+	log.Debug("execute " + query.Name)
+	rows, err := db.QueryContext(ctx, query.Sql)
+	if err != nil {
+		return nil, fmt.Errorf("error in query '%s': %v", query.Sql, err)
+	}
+	defer rows.Close()
+
+	cols, _ := rows.Columns()
+	vals := make([]interface{}, len(cols))
+
+	labelColumns := []int{}
+	for _, label := range query.Labels {
+		i := findColumn(cols, label)
+		if i == -1 {
+			logger.Errorln("Label column '" + label + "' not found")
+		}
+		labelColumns = append(labelColumns, i)
+	}
+
+	samples := []customSample{}
+	// an invalid sample, e.g. a label value which is not UTF-8, is dropped
+	add := func(m QueryMetric, labelValues []string, metric prometheus.Metric, err error) {
+		if err != nil {
+			logger.Errorln(err)
+			e.customErrors.WithLabelValues(query.Name, config.Database, config.Instance).Inc()
+			return
+		}
+		key := customFQName(query, m) + "\xff" + strings.Join(labelValues, "\xff")
+		samples = append(samples, customSample{key: key, metric: metric})
+	}
+	var rownum int = 1
+	for rows.Next() {
+		for i := range cols {
+			vals[i] = &vals[i]
+		}
+		if err := rows.Scan(vals...); err != nil {
+			return nil, err
+		}
+
+		labelValues := []string{}
+		for _, i := range labelColumns {
+			if i == -1 {
+				labelValues = append(labelValues, "")
+				continue
+			}
+			labelValues = append(labelValues, toLabel(vals[i]))
+		}
+		// the values of the labels after the query labels, see customLabels
+		rowLabels := func(m QueryMetric) []string {
+			values := append([]string{}, labelValues...)
+			if !m.typed() {
+				values = append(values, m.Column)
+			}
+			values = append(values, config.Database, config.Instance)
+			if pNoRownum == false {
+				values = append(values, strconv.Itoa(rownum))
+			}
+			return values
+		}
+
+		for _, m := range query.Metrics {
+			desc := e.descFor(query, m)
+			if m.Type == typeHistogram {
+				metric, err := histogramMetric(desc, m, cols, vals, rowLabels(m))
+				add(m, rowLabels(m), metric, err)
+				continue
+			}
+
+			i := findColumn(cols, m.Column)
+			if i == -1 {
+				logger.Errorln("Metric column '" + m.Column + "' not found")
+				continue
+			}
+			if value, ok := toFloat(vals[i]); ok {
+				metric, err := prometheus.NewConstMetric(desc, m.valueType(), value, rowLabels(m)...)
+				add(m, rowLabels(m), metric, err)
+			}
+		}
+
+		rownum++
+	}
+	return samples, rows.Err()
+}
+
+// histogramMetric maps the bucket, count and sum columns of a row to a histogram.
+func histogramMetric(desc *prometheus.Desc, m QueryMetric, cols []string, vals []interface{}, labelValues []string) (prometheus.Metric, error) {
+	value := func(column string) (float64, error) {
+		i := findColumn(cols, column)
+		if i == -1 {
+			return 0, fmt.Errorf("histogram column '%s' not found", column)
+		}
+		v, ok := toFloat(vals[i])
+		if !ok {
+			return 0, fmt.Errorf("histogram column '%s' is not numeric", column)
+		}
+		return v, nil
+	}
+
+	buckets := map[float64]uint64{}
+	for column, le := range m.Buckets {
+		v, err := value(column)
+		if err != nil {
+			return nil, err
+		}
+		buckets[le] = uint64(v)
+	}
+	count, err := value(m.Count)
+	if err != nil {
+		return nil, err
+	}
+	sum, err := value(m.Sum)
+	if err != nil {
+		return nil, err
+	}
+	return prometheus.NewConstHistogram(desc, uint64(count), sum, buckets, labelValues...)
+}
+
+// descFor returns the descriptor registered for the metric of a query.
+func (e *Exporter) descFor(query Query, m QueryMetric) *prometheus.Desc {
+	if desc, ok := e.custom[customFQName(query, m)]; ok {
+		return desc
+	}
+	return customDesc(query, m)
+}
+
+// customSample is a sample of a custom query, the key is its name and label values.
+type customSample struct {
+	key    string
+	metric prometheus.Metric
+}
+
+// customSamples are the samples of the custom queries of one scrape. A sample
+// with the name and label values of an earlier one replaces it, so rows with
+// the same labels don't fail the scrape.
+type customSamples struct {
+	mu      sync.Mutex
+	keys    []string
+	samples map[string]prometheus.Metric
+}
+
+func newCustomSamples() *customSamples {
+	return &customSamples{samples: map[string]prometheus.Metric{}}
+}
+
+func (s *customSamples) add(samples []customSample) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, sample := range samples {
+		if _, ok := s.samples[sample.key]; !ok {
+			s.keys = append(s.keys, sample.key)
+		}
+		s.samples[sample.key] = sample.metric
+	}
+}
+
+func (s *customSamples) collect(ch chan<- prometheus.Metric) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, key := range s.keys {
+		ch <- s.samples[key]
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"database/sql/driver"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestCustomQuerySamples(t *testing.T) {
+	defer func(v bool) { *pNoRownum = v }(*pNoRownum)
+
+	tests := []struct {
+		name     string
+		noRownum bool
+		rows     [][]driver.Value
+		// values of oracledb_custom_rows by label value
+		want   map[string]float64
+		errors float64
+	}{
+		{
+			name: "rows with rownum",
+			rows: [][]driver.Value{{"a", int64(1)}, {"b", int64(2)}},
+			want: map[string]float64{"a": 1, "b": 2},
+		},
+		{
+			name:     "same labels without rownum, the last row wins",
+			noRownum: true,
+			rows:     [][]driver.Value{{"a", int64(1)}, {"a", int64(2)}, {"b", int64(3)}},
+			want:     map[string]float64{"a": 2, "b": 3},
+		},
+		{
+			name:     "label value which is not UTF-8 is dropped",
+			noRownum: true,
+			rows:     [][]driver.Value{{"\xff\xfe", int64(1)}, {"b", int64(2)}},
+			want:     map[string]float64{"b": 2},
+			errors:   1,
+		},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			*pNoRownum = tt.noRownum
+			name := "custom_samples_" + string(rune('a'+i))
+			config := addFakeDB(name, &fakeDB{results: upResults(
+				fakeResult{match: "from custom_rows", columns: []string{"NAME", "VALUE"}, rows: tt.rows},
+			)})
+			defer dropPool(config)
+			query := Query{Name: "rows", Help: "rows", Sql: "select name, value from custom_rows",
+				Labels: []string{"name"}, Metrics: []QueryMetric{{Column: "value"}}}
+			config.Queries = []Query{query}
+
+			e := NewExporter()
+			e.configs = []*Config{config}
+			e.custom[customFQName(query, query.Metrics[0])] = customDesc(query, query.Metrics[0])
+			registry := prometheus.NewRegistry()
+			registry.MustRegister(e)
+
+			families, err := registry.Gather()
+			if err != nil {
+				t.Fatalf("scrape failed: %v", err)
+			}
+			got := map[string]float64{}
+			for _, family := range families {
+				if family.GetName() != "oracledb_custom_rows" {
+					continue
+				}
+				for _, m := range family.GetMetric() {
+					for _, l := range m.GetLabel() {
+						if l.GetName() == "name" {
+							got[l.GetValue()] = m.GetGauge().GetValue()
+						}
+					}
+				}
+			}
+			if len(got) != len(tt.want) {
+				t.Errorf("samples %v, want %v", got, tt.want)
+			}
+			for label, value := range tt.want {
+				if got[label] != value {
+					t.Errorf("sample %s = %v, want %v", label, got[label], value)
+				}
+			}
+			if errors := testutil.ToFloat64(e.customErrors.WithLabelValues("rows", name, name)); errors != tt.errors {
+				t.Errorf("custom_metric_errors_total = %v, want %v", errors, tt.errors)
+			}
+		})
+	}
+}
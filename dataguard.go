@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+)
+
+// ScrapeDataguard collects the lag of a standby from v$dataguard_stats, the
+// role from v$database and the state of the archive destinations.
+func (e *Exporter) ScrapeDataguard(ctx context.Context, config *Config) error {
+	db := config.db
+
+	var role, protection, switchover string
+	if err := db.QueryRowContext(ctx, `select database_role, protection_mode, switchover_status
+                                 from v$database`).Scan(&role, &protection, &switchover); err != nil {
+		return err
+	}
+	e.dataguardRole.WithLabelValues(config.Database, config.Instance, role).Set(1)
+	e.dataguardProtection.WithLabelValues(config.Database, config.Instance, protection).Set(1)
+	e.dataguardSwitchover.WithLabelValues(config.Database, config.Instance, switchover).Set(1)
+
+	// the values are intervals like +00 00:00:05, only a standby has them
+	rows, err := db.QueryContext(ctx, `select name, extract(day from i)*86400 + extract(hour from i)*3600
+                                        + extract(minute from i)*60 + extract(second from i)
+                                 from (select name, to_dsinterval(value) i from v$dataguard_stats
+                                       where name in ('transport lag','apply lag','apply finish time') and value is not null)`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name string
+		var value float64
+		if err := rows.Scan(&name, &value); err != nil {
+			return err
+		}
+		switch name {
+		case "transport lag":
+			e.dataguardLag.WithLabelValues(config.Database, config.Instance, "transport").Set(value)
+		case "apply lag":
+			e.dataguardLag.WithLabelValues(config.Database, config.Instance, "apply").Set(value)
+		case "apply finish time":
+			e.dataguardApplyFinish.WithLabelValues(config.Database, config.Instance).Set(value)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	rows, err = db.QueryContext(ctx, `select dest_name, status, error from v$archive_dest_status
+                                 where status != 'INACTIVE'`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name string
+		var status string
+		var destError sql.NullString
+		if err := rows.Scan(&name, &status, &destError); err != nil {
+			return err
+		}
+		e.archiveDestStatus.WithLabelValues(config.Database, config.Instance, name, status).Set(1)
+		e.archiveDestError.WithLabelValues(config.Database, config.Instance, name).Set(boolValue(status == "ERROR" || destError.String != ""))
+	}
+	return rows.Err()
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/common/log"
+)
+
+var (
+	dbMaxOpenConns    = flag.Int("db.max-open-conns", 3, "Maximum number of open connections per database (can be overridden per connection).")
+	dbMaxIdleConns    = flag.Int("db.max-idle-conns", 3, "Maximum number of idle connections per database (can be overridden per connection).")
+	dbConnMaxLifetime = flag.Duration("db.conn-max-lifetime", 30*time.Minute, "Maximum time a connection is reused before it is reopened, 0 keeps it forever (can be overridden per connection).")
+
+	// long-lived connection pools, shared by all exporters using the same connection
+	pools   = map[string]*sql.DB{}
+	poolsMu sync.Mutex
+	// the scrape jobs of a pool may use all but one of its connections, the
+	// last one is left for checkPool and inspect of the next scrape, even if
+	// the jobs of an earlier scrape still hang after its deadline
+	jobSlots = map[string]chan struct{}{}
+)
+
+// poolKey identifies the connection pool of a configured connection.
+func (c *Config) poolKey() string {
+	return c.Database + "/" + c.Instance + "/" + c.safeConnection()
+}
+
+// openPool returns the connection pool of the config, opening it on first use.
+func openPool(c *Config) (*sql.DB, error) {
+	poolsMu.Lock()
+	defer poolsMu.Unlock()
+
+	if db, ok := pools[c.poolKey()]; ok {
+		return db, nil
+	}
+
+	log.Infoln("open connection pool for " + c.Database + "/" + c.Instance)
+	var db *sql.DB
+	// all logons of a client which reads TNS_ADMIN are serialized against
+	// the ones which change it
+	if c.dbDriver().tnsAdminEnv {
+		connector, err := newTnsAdminConnector(c.dbDriver().sqlName, c.connectString(), c.TnsAdmin)
+		if err != nil {
+			return nil, c.redact(err)
+		}
+		db = sql.OpenDB(connector)
+	} else {
+		var err error
+		if db, err = sql.Open(c.dbDriver().sqlName, c.connectString()); err != nil {
+			return nil, c.redact(err)
+		}
+	}
+
+	maxOpen := *dbMaxOpenConns
+	if c.MaxOpenConns > 0 {
+		maxOpen = c.MaxOpenConns
+	}
+	maxIdle := *dbMaxIdleConns
+	if c.MaxIdleConns > 0 {
+		maxIdle = c.MaxIdleConns
+	}
+	lifetime := *dbConnMaxLifetime
+	if c.ConnMaxLifetime > 0 {
+		lifetime = c.ConnMaxLifetime
+	}
+	db.SetMaxOpenConns(maxOpen)
+	db.SetMaxIdleConns(maxIdle)
+	db.SetConnMaxLifetime(lifetime)
+
+	pools[c.poolKey()] = db
+	if maxOpen > 1 {
+		jobSlots[c.poolKey()] = make(chan struct{}, maxOpen-1)
+	}
+	return db, nil
+}
+
+// acquireJobSlot waits until a scrape job may use a connection of the pool of
+// the config, or the context ends. The returned function frees the slot.
+func acquireJobSlot(ctx context.Context, c *Config) (func(), error) {
+	poolsMu.Lock()
+	slots := jobSlots[c.poolKey()]
+	poolsMu.Unlock()
+	if slots == nil {
+		return func() {}, nil
+	}
+	select {
+	case slots <- struct{}{}:
+		return func() { <-slots }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// dropPool closes the connection pool of the config, the next openPool reconnects.
+func dropPool(c *Config) {
+	poolsMu.Lock()
+	defer poolsMu.Unlock()
+
+	if db, ok := pools[c.poolKey()]; ok {
+		db.Close()
+		delete(pools, c.poolKey())
+		delete(jobSlots, c.poolKey())
+		log.Infoln("closed connection pool for " + c.Database + "/" + c.Instance)
+	}
+}
+
+// checkPool verifies that the pool can reach the database.
+func checkPool(ctx context.Context, db *sql.DB) error {
+	rows, err := db.QueryContext(ctx, `select 1 from dual`)
+	if err != nil {
+		return err
+	}
+	return rows.Close()
+}
+
+// inspect finds out whether the instance is open, ASM instances and instances
+// which are only started or mounted can't run all queries, whether it has a
+// mounted database, which ASM instances never have, and whether it is a
+// container database.
+func inspect(ctx context.Context, db *sql.DB) (open, mounted, cdb bool, err error) {
+	var status string
+	if err := db.QueryRowContext(ctx, `select status from v$instance`).Scan(&status); err != nil {
+		return false, false, false, err
+	}
+
+	// v$database.cdb is missing before 12c, v$database itself is missing in
+	// ASM and on a started instance
+	var isCdb string
+	if err := db.QueryRowContext(ctx, `select cdb from v$database`).Scan(&isCdb); err != nil {
+		isCdb = "NO"
+		var one int
+		mounted = db.QueryRowContext(ctx, `select 1 from v$database`).Scan(&one) == nil
+	} else {
+		mounted = true
+	}
+	return strings.HasPrefix(status, "OPEN"), mounted, isCdb == "YES", nil
+}
+
+// dropStalePools closes the pools of connections which were removed or changed
+// on a reload, the pools of unchanged connections are kept.
+func dropStalePools(old, current []Config) {
+	kept := map[string]bool{}
+	for i := range old {
+		for j := range current {
+			o, c := &old[i], &current[j]
+			if o.poolKey() == c.poolKey() && o.connectString() == c.connectString() && o.TnsAdmin == c.TnsAdmin && o.MaxOpenConns == c.MaxOpenConns &&
+				o.MaxIdleConns == c.MaxIdleConns && o.ConnMaxLifetime == c.ConnMaxLifetime {
+				kept[o.poolKey()] = true
+			}
+		}
+	}
+	for i := range old {
+		if !kept[old[i].poolKey()] {
+			dropPool(&old[i])
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"flag"
+	"sort"
+	"strings"
+)
+
+var dbDriverName = flag.String("db.driver", "", "Default Oracle driver of the connections, oci8 (Oracle client) or goora (pure Go), default oci8 if it is built in (can be overridden per connection).")
+
+// dbDriver is an Oracle driver the exporter is built with.
+type dbDriver struct {
+	// name registered with database/sql
+	sqlName string
+	// connectString builds the data source name of the connection for the driver
+	connectString func(c *Config) string
+	// administrative privileges the driver can connect with
+	roles []string
+	// whether the client reads the tns_admin directory from the TNS_ADMIN
+	// environment variable, otherwise connectString passes it
+	tnsAdminEnv bool
+}
+
+// dbDrivers are the drivers built in, they register themselves in init.
+var dbDrivers = map[string]*dbDriver{}
+
+// driverName returns the name of the driver of the connection.
+func (c *Config) driverName() string {
+	if c.Driver != "" {
+		return c.Driver
+	}
+	if *dbDriverName != "" {
+		return *dbDriverName
+	}
+	if _, ok := dbDrivers["oci8"]; ok {
+		return "oci8"
+	}
+	return "goora"
+}
+
+// dbDriver returns the driver of the connection, nil if it is not built in.
+func (c *Config) dbDriver() *dbDriver {
+	return dbDrivers[c.driverName()]
+}
+
+// supportsRole tells whether the driver can connect with the administrative privilege.
+func (d *dbDriver) supportsRole(role string) bool {
+	for _, r := range d.roles {
+		if strings.EqualFold(r, role) {
+			return true
+		}
+	}
+	return false
+}
+
+// dbDriverNames returns the sorted names of the built in drivers.
+func dbDriverNames() []string {
+	names := []string{}
+	for name := range dbDrivers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
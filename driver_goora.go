@@ -0,0 +1,28 @@
+package main
+
+import (
+	"strings"
+
+	go_ora "github.com/sijms/go-ora/v2"
+)
+
+func init() {
+	dbDrivers["goora"] = &dbDriver{sqlName: "oracle", connectString: goOraConnectString,
+		roles: []string{"sysdba", "sysoper"}}
+}
+
+// goOraConnectString returns the URL for go-ora. The dsn is host:port/service
+// or a connect descriptor, go-ora doesn't resolve aliases of a tnsnames.ora.
+func goOraConnectString(c *Config) string {
+	user, password, dsn := c.credentials()
+	options := map[string]string{}
+	if c.TnsAdmin != "" {
+		options["WALLET"] = c.TnsAdmin
+	} else if user == "" {
+		options["AUTH TYPE"] = "OS"
+	}
+	if c.Role != "" {
+		options["DBA PRIVILEGE"] = strings.ToUpper(c.Role)
+	}
+	return go_ora.BuildJDBC(user, password, dsn, options)
+}
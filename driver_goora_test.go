@@ -0,0 +1,69 @@
+package main
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+func TestGoOraConnectString(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   Config
+		user     string
+		password string
+		// the query options of the URL, connStr is the dsn
+		options map[string]string
+	}{
+		{
+			name:     "connection string",
+			config:   Config{Connection: "u/p@db:1521/svc"},
+			user:     "u",
+			password: "p",
+			options:  map[string]string{"connStr": "db:1521/svc"},
+		},
+		{
+			name:     "user, password and dsn",
+			config:   Config{User: "u", Password: "p@ss/word", Dsn: "db:1521/svc"},
+			user:     "u",
+			password: "p@ss/word",
+			options:  map[string]string{"connStr": "db:1521/svc"},
+		},
+		{
+			name:    "dsn without user is OS authentication",
+			config:  Config{Dsn: "db:1521/svc"},
+			options: map[string]string{"connStr": "db:1521/svc", "AUTH TYPE": "OS"},
+		},
+		{
+			name:    "dsn with tns_admin uses the wallet",
+			config:  Config{Dsn: "db:1521/svc", TnsAdmin: "/etc/wallet"},
+			options: map[string]string{"connStr": "db:1521/svc", "WALLET": "/etc/wallet"},
+		},
+		{
+			name:     "role",
+			config:   Config{User: "sys", Password: "p", Dsn: "db:1521/+ASM", Role: "sysdba"},
+			user:     "sys",
+			password: "p",
+			options:  map[string]string{"connStr": "db:1521/+ASM", "DBA PRIVILEGE": "SYSDBA"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u, err := url.Parse(goOraConnectString(&tt.config))
+			if err != nil {
+				t.Fatal(err)
+			}
+			password, _ := u.User.Password()
+			if u.User.Username() != tt.user || password != tt.password {
+				t.Errorf("user %q password %q, want %q %q", u.User.Username(), password, tt.user, tt.password)
+			}
+			options := map[string]string{}
+			for key, values := range u.Query() {
+				options[key] = values[0]
+			}
+			if !reflect.DeepEqual(options, tt.options) {
+				t.Errorf("options %v, want %v", options, tt.options)
+			}
+		})
+	}
+}
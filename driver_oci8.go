@@ -0,0 +1,36 @@
+// the Oracle client driver, left out by the goora build tag for a static binary
+//go:build !goora
+// +build !goora
+
+package main
+
+import (
+	"strings"
+
+	_ "github.com/mattn/go-oci8"
+)
+
+func init() {
+	dbDrivers["oci8"] = &dbDriver{sqlName: "oci8", connectString: oci8ConnectString,
+		roles: []string{"sysdba", "sysasm", "sysoper"}, tnsAdminEnv: true}
+}
+
+// oci8ConnectString returns <user>/<pass>@<tnsname>, or /@<tnsname> for the
+// wallet and OS authentication, with ?as=<role> for administrative privileges.
+func oci8ConnectString(c *Config) string {
+	var s string
+	if c.Connection != "" {
+		s = c.Connection
+	} else if user, password, dsn := c.credentials(); user == "" {
+		s = "/@" + dsn
+	} else {
+		s = user + "/" + password + "@" + dsn
+	}
+	if c.Role == "" {
+		return s
+	}
+	if strings.Contains(s, "?") {
+		return s + "&as=" + strings.ToLower(c.Role)
+	}
+	return s + "?as=" + strings.ToLower(c.Role)
+}
@@ -0,0 +1,26 @@
+//go:build !goora
+// +build !goora
+
+package main
+
+import "testing"
+
+func TestOci8ConnectString(t *testing.T) {
+	tests := []struct {
+		name   string
+		config Config
+		want   string
+	}{
+		{"connection string", Config{Connection: "u/p@DEVELOP"}, "u/p@DEVELOP"},
+		{"user, password and dsn", Config{User: "u", Password: "p", Dsn: "DEVELOP"}, "u/p@DEVELOP"},
+		{"wallet", Config{Dsn: "develop_monitoring"}, "/@develop_monitoring"},
+		{"role", Config{User: "sys", Password: "p", Dsn: "ASM", Role: "SYSASM"}, "sys/p@ASM?as=sysasm"},
+		{"role of a wallet", Config{Dsn: "ASM", Role: "sysdba"}, "/@ASM?as=sysdba"},
+		{"role after options", Config{Connection: "u/p@DEVELOP?prefetch_rows=100", Role: "sysdba"}, "u/p@DEVELOP?prefetch_rows=100&as=sysdba"},
+	}
+	for _, tt := range tests {
+		if got := oci8ConnectString(&tt.config); got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,21 @@
+package main
+
+import (
+	"os"
+	"testing"
+)
+
+func TestExampleConfig(t *testing.T) {
+	defer func(v string) { *queriesDir = v }(*queriesDir)
+	*queriesDir = "queries.example"
+	os.Setenv("STAGE_PASSWORD", "secret")
+	defer os.Unsetenv("STAGE_PASSWORD")
+
+	conf, err := readConfig("oracle.conf.example")
+	if err != nil {
+		t.Fatalf("oracle.conf.example doesn't load:\n%v", err)
+	}
+	if got := conf.Cfgs[1].Password; got != "secret" {
+		t.Errorf("password of STAGE is %q, want the one of STAGE_PASSWORD", got)
+	}
+}
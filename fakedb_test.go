@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+)
+
+// fakeDriver is a database/sql driver for the tests, the DSN is the name of a
+// fakeDB with the results of its queries.
+type fakeDriver struct{}
+
+// fakeDB answers queries by the first result whose match is part of the SQL.
+type fakeDB struct {
+	// Open blocks until it is closed, like a hanging logon
+	hang    chan struct{}
+	results []fakeResult
+
+	mu      sync.Mutex
+	queries []string
+}
+
+type fakeResult struct {
+	match   string
+	columns []string
+	rows    [][]driver.Value
+	err     error
+	// the query blocks until it is closed, ignoring the context like a hanging driver
+	wait chan struct{}
+}
+
+var (
+	fakeDBs   = map[string]*fakeDB{}
+	fakeDBsMu sync.Mutex
+)
+
+func init() {
+	sql.Register("fakedb", fakeDriver{})
+	dbDrivers["fake"] = &dbDriver{
+		sqlName:       "fakedb",
+		connectString: func(c *Config) string { return c.Dsn },
+	}
+}
+
+// addFakeDB registers the fake database under the name and returns a config
+// connecting to it.
+func addFakeDB(name string, db *fakeDB) *Config {
+	fakeDBsMu.Lock()
+	fakeDBs[name] = db
+	fakeDBsMu.Unlock()
+	return &Config{Database: name, Instance: name, Dsn: name, Driver: "fake"}
+}
+
+// upResults are the queries of connect for an open non-CDB instance.
+func upResults(results ...fakeResult) []fakeResult {
+	return append(results,
+		fakeResult{match: "from dual", columns: []string{"1"}, rows: [][]driver.Value{{int64(1)}}},
+		fakeResult{match: "v$instance", columns: []string{"status"}, rows: [][]driver.Value{{"OPEN"}}},
+		fakeResult{match: "v$database", columns: []string{"cdb"}, rows: [][]driver.Value{{"NO"}}},
+	)
+}
+
+func (fakeDriver) Open(name string) (driver.Conn, error) {
+	fakeDBsMu.Lock()
+	db, ok := fakeDBs[name]
+	fakeDBsMu.Unlock()
+	if !ok {
+		return nil, errors.New("ORA-12154: TNS:could not resolve the connect identifier specified")
+	}
+	if db.hang != nil {
+		<-db.hang
+		return nil, errors.New("ORA-12170: TNS:Connect timeout occurred")
+	}
+	return &fakeConn{db: db}, nil
+}
+
+type fakeConn struct {
+	db *fakeDB
+}
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, errors.New("not supported")
+}
+
+func (c *fakeConn) Close() error {
+	return nil
+}
+
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	return nil, errors.New("not supported")
+}
+
+func (c *fakeConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	c.db.mu.Lock()
+	c.db.queries = append(c.db.queries, query)
+	c.db.mu.Unlock()
+	for _, r := range c.db.results {
+		if strings.Contains(query, r.match) {
+			if r.wait != nil {
+				<-r.wait
+			}
+			if r.err != nil {
+				return nil, r.err
+			}
+			return &fakeRows{columns: r.columns, rows: r.rows}, nil
+		}
+	}
+	return nil, errors.New("ORA-00942: table or view does not exist")
+}
+
+// count returns how often a query containing match was run.
+func (db *fakeDB) count(match string) int {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	n := 0
+	for _, q := range db.queries {
+		if strings.Contains(q, match) {
+			n++
+		}
+	}
+	return n
+}
+
+type fakeRows struct {
+	columns []string
+	rows    [][]driver.Value
+}
+
+func (r *fakeRows) Columns() []string {
+	return r.columns
+}
+
+func (r *fakeRows) Close() error {
+	return nil
+}
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
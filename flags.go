@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// regexpFlag is a flag with a regular expression which must match the whole value.
+type regexpFlag struct {
+	*regexp.Regexp
+	expr string
+}
+
+func (f *regexpFlag) String() string {
+	if f == nil {
+		return ""
+	}
+	return f.expr
+}
+
+func (f *regexpFlag) Set(value string) error {
+	if _, err := regexp.Compile(value); err != nil {
+		return err
+	}
+	f.Regexp = regexp.MustCompile("^(?:" + value + ")$")
+	f.expr = value
+	return nil
+}
+
+// matches tells whether the flag is set and matches the value.
+func (f *regexpFlag) matches(value string) bool {
+	return f.Regexp != nil && f.MatchString(value)
+}
+
+// listFlag is a flag with a comma separated list of values.
+type listFlag []string
+
+func (f *listFlag) String() string {
+	if f == nil {
+		return ""
+	}
+	return strings.Join(*f, ",")
+}
+
+func (f *listFlag) Set(value string) error {
+	*f = nil
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			*f = append(*f, v)
+		}
+	}
+	return nil
+}
+
+// bindList returns the bind variables :start, :start+1, ... for the values
+// of an IN list and the values as arguments.
+func bindList(start int, values []string) (string, []interface{}) {
+	binds := make([]string, len(values))
+	args := make([]interface{}, len(values))
+	for i, v := range values {
+		binds[i] = fmt.Sprintf(":%d", start+i)
+		args[i] = v
+	}
+	return strings.Join(binds, ","), args
+}
+
+// choiceFlag is a flag with one of a fixed set of values.
+type choiceFlag struct {
+	value   string
+	choices []string
+}
+
+func (f *choiceFlag) String() string {
+	if f == nil {
+		return ""
+	}
+	return f.value
+}
+
+func (f *choiceFlag) Set(value string) error {
+	for _, c := range f.choices {
+		if value == c {
+			f.value = value
+			return nil
+		}
+	}
+	return fmt.Errorf("must be one of %s", strings.Join(f.choices, ", "))
+}
@@ -1,357 +1,551 @@
 package main
 
 import (
-     "database/sql"
-     "flag"
-     "net"
-     "net/http"
-     "strconv"
-     "time"
-     "fmt"
-
-    //"io/ioutil"
-    //"gopkg.in/yaml.v2"
-     _ "github.com/mattn/go-oci8"
-     "github.com/prometheus/client_golang/prometheus"
-     "github.com/prometheus/client_golang/prometheus/promhttp"
-     "github.com/prometheus/common/log"
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	//"io/ioutil"
+	//"gopkg.in/yaml.v2"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/prometheus/common/log"
 )
 
 // Metric name parts.
 const (
-     namespace = "oracledb"
-     exporter  = "exporter"
+	namespace = "oracledb"
+	exporter  = "exporter"
 )
 
 // Exporter collects Oracle DB metrics. It implements prometheus.Collector.
 type Exporter struct {
-    duration, error *prometheus.GaugeVec
-    totalScrapes    *prometheus.CounterVec
-    scrapeErrors    *prometheus.CounterVec
-     session         *prometheus.GaugeVec
-     sysstat         *prometheus.GaugeVec
-     waitclass       *prometheus.GaugeVec
-     sysmetric       *prometheus.GaugeVec
-     interconnect    *prometheus.GaugeVec
-     uptime          *prometheus.GaugeVec
-     up              *prometheus.GaugeVec
-     tablespace      *prometheus.GaugeVec
-     recovery        *prometheus.GaugeVec
-     redo            *prometheus.GaugeVec
-     cache           *prometheus.GaugeVec
-     alertlog        *prometheus.GaugeVec
-     alertdate       *prometheus.GaugeVec
-     services        *prometheus.GaugeVec
-     parameter       *prometheus.GaugeVec
-     //query           *prometheus.GaugeVec
-     asmspace   *prometheus.GaugeVec
-	 //config          Config
-	 configs     []*Config
-     tablerows  *prometheus.GaugeVec
-     tablebytes *prometheus.GaugeVec
-     indexbytes *prometheus.GaugeVec
-     lobbytes   *prometheus.GaugeVec
-     lastIp     string
-     vTabRows   bool
-     vTabBytes  bool
-     vIndBytes  bool
-     vLobBytes  bool
-     vRecovery  bool
-     custom     map[string]*prometheus.GaugeVec
+	duration, error *prometheus.GaugeVec
+	totalScrapes    *prometheus.CounterVec
+	scrapeErrors    *prometheus.CounterVec
+	timeouts        *prometheus.CounterVec
+	collectorTime   *prometheus.GaugeVec
+	collectorUp     *prometheus.GaugeVec
+	lastSuccess     *prometheus.GaugeVec
+	queryAge        *prometheus.GaugeVec
+	connectError    *prometheus.GaugeVec
+	session         *prometheus.GaugeVec
+	sysstat         *counterVec
+	waitclass       *prometheus.GaugeVec
+	sysmetric       *prometheus.GaugeVec
+	interconnect    *prometheus.GaugeVec
+	uptime          *prometheus.GaugeVec
+	up              *prometheus.GaugeVec
+	tablespace      *prometheus.GaugeVec
+	recovery        *prometheus.GaugeVec
+	redo            *prometheus.GaugeVec
+	cache           *prometheus.GaugeVec
+	alertlog        *prometheus.GaugeVec
+	alertdate       *prometheus.GaugeVec
+	services        *prometheus.GaugeVec
+	parameter       *prometheus.GaugeVec
+	parameterInfo   *prometheus.GaugeVec
+	//query           *prometheus.GaugeVec
+	asmspace *prometheus.GaugeVec
+	//config          Config
+	configs    []*Config
+	tablerows  *prometheus.GaugeVec
+	tablebytes *prometheus.GaugeVec
+	indexbytes *prometheus.GaugeVec
+	lobbytes   *prometheus.GaugeVec
+	// pluggable databases of a CDB
+	pdbOpenMode   *prometheus.GaugeVec
+	pdbRestricted *prometheus.GaugeVec
+	pdbSize       *prometheus.GaugeVec
+	pdbRecovery   *prometheus.GaugeVec
+	pdbResource   *prometheus.GaugeVec
+	// Data Guard
+	dataguardRole        *prometheus.GaugeVec
+	dataguardProtection  *prometheus.GaugeVec
+	dataguardSwitchover  *prometheus.GaugeVec
+	dataguardLag         *prometheus.GaugeVec
+	dataguardApplyFinish *prometheus.GaugeVec
+	archiveDestStatus    *prometheus.GaugeVec
+	archiveDestError     *prometheus.GaugeVec
+	// redo and archived logs
+	redoSwitches     *counterVec
+	redoLogStatus    *prometheus.GaugeVec
+	redoLogArchived  *prometheus.GaugeVec
+	archivedFiles    *prometheus.GaugeVec
+	archivedBytes    *prometheus.GaugeVec
+	archiveDest      *prometheus.GaugeVec
+	archiveDestSpace *prometheus.GaugeVec
+	// ASM diskgroups, disks and operations
+	asmDiskgroupState     *prometheus.GaugeVec
+	asmDiskgroupSpace     *prometheus.GaugeVec
+	asmOfflineDisks       *prometheus.GaugeVec
+	asmDiskMountStatus    *prometheus.GaugeVec
+	asmDiskModeStatus     *prometheus.GaugeVec
+	asmDiskState          *prometheus.GaugeVec
+	asmDiskErrors         *counterVec
+	asmOperationProgress  *prometheus.GaugeVec
+	asmOperationRemaining *prometheus.GaugeVec
+	asmOperationPower     *prometheus.GaugeVec
+	// wait events
+	waitEventWaits    *counterVec
+	waitEventTimeouts *counterVec
+	waitEventTime     *counterVec
+	// v$resource_limit
+	resourceCurrent *prometheus.GaugeVec
+	resourceMax     *prometheus.GaugeVec
+	resourceLimit   *prometheus.GaugeVec
+	lastIp          string
+	collect         map[string]bool
+	// descriptors of the custom query metrics by name
+	custom       map[string]*prometheus.Desc
+	customErrors *prometheus.CounterVec
+	timeout      time.Duration
+	mu           sync.Mutex
 }
 
 var (
-     // Version will be set at build time.
-     Version       = "1.1.6"
-     listenAddress = flag.String("web.listen-address", ":9161", "Address to listen on for web interface and telemetry.")
-     metricPath    = flag.String("web.telemetry-path", "/metrics", "Path under which to expose metrics.")
-     pMetrics      = flag.Bool("defaultmetrics", true, "Expose standard metrics")
-     pTabRows      = flag.Bool("tablerows", false, "Expose Table rows (CAN TAKE VERY LONG)")
-     pTabBytes     = flag.Bool("tablebytes", false, "Expose Table size (CAN TAKE VERY LONG)")
-     pIndBytes     = flag.Bool("indexbytes", false, "Expose Index size for any Table (CAN TAKE VERY LONG)")
-     pLobBytes     = flag.Bool("lobbytes", false, "Expose Lobs size for any Table (CAN TAKE VERY LONG)")
-    pNoRownum     = flag.Bool("norownum", false, "omit rownum label in custom metrics")
-     pRecovery     = flag.Bool("recovery", false, "Expose Recovery percentage usage of FRA (CAN TAKE VERY LONG)")
-     configFile    = flag.String("configfile", "oracle.conf", "ConfigurationFile in YAML format.")
-     logFile       = flag.String("logfile", "exporter.log", "Logfile for parsed Oracle Alerts.")
-     accessFile    = flag.String("accessfile", "access.conf", "Last access for parsed Oracle Alerts.")
-     landingPage   = []byte(`<html>
+	// Version will be set at build time.
+	Version       = "1.1.6"
+	listenAddress = flag.String("web.listen-address", ":9161", "Address to listen on for web interface and telemetry.")
+	metricPath    = flag.String("web.telemetry-path", "/metrics", "Path under which to expose metrics.")
+	pMetrics      = flag.Bool("defaultmetrics", true, "Expose standard metrics (deprecated, use -no-collector.<name>)")
+	pTabRows      = flag.Bool("tablerows", false, "Expose Table rows (deprecated, use -collector.tablerows)")
+	pTabBytes     = flag.Bool("tablebytes", false, "Expose Table size (deprecated, use -collector.tablebytes)")
+	pIndBytes     = flag.Bool("indexbytes", false, "Expose Index size for any Table (deprecated, use -collector.indexbytes)")
+	pLobBytes     = flag.Bool("lobbytes", false, "Expose Lobs size for any Table (deprecated, use -collector.lobbytes)")
+	pNoRownum     = flag.Bool("norownum", false, "omit rownum label in custom metrics")
+	pRecovery     = flag.Bool("recovery", false, "Expose Recovery percentage usage of FRA (deprecated, use -collector.recovery)")
+	configFile    = flag.String("configfile", "oracle.conf", "ConfigurationFile in YAML format.")
+	logFile       = flag.String("logfile", "exporter.log", "Logfile for parsed Oracle Alerts.")
+	accessFile    = flag.String("accessfile", "access.conf", "Last access for parsed Oracle Alerts.")
+	landingPage   = []byte(`<html>
                           <head><title>Prometheus Oracle exporter</title></head>
                           <body>
                             <h1>Prometheus Oracle exporter</h1><p>
                             <a href='` + *metricPath + `'>Metrics</a></p>
                             <a href='` + *metricPath + `?target=database name'>Metrics only one database (all instances will be scraped)</a></p>
-                            <a href='` + *metricPath + `?tablerows=true'>Metrics with tablerows</a></p>
-                            <a href='` + *metricPath + `?tablebytes=true'>Metrics with tablebytes</a></p>
-                            <a href='` + *metricPath + `?indexbytes=true'>Metrics with indexbytes</a></p>
-                            <a href='` + *metricPath + `?lobbytes=true'>Metrics with lobbytes</a></p>
-                            <a href='` + *metricPath + `?recovery=true'>Metrics with recovery</a></p>
+                            <a href='` + *metricPath + `?collect[]=tablerows'>Metrics only from tablerows</a></p>
+                            <a href='` + *metricPath + `?collect[]=tablebytes'>Metrics only from tablebytes</a></p>
+                            <a href='` + *metricPath + `?collect[]=indexbytes'>Metrics only from indexbytes</a></p>
+                            <a href='` + *metricPath + `?collect[]=lobbytes'>Metrics only from lobbytes</a></p>
+                            <a href='` + *metricPath + `?collect[]=recovery'>Metrics only from recovery</a></p>
                           </body>
                                 </html>`)
 
-  //configs Configs
-  metricsExporter *Exporter
-  handlers = map[string]http.Handler {}
+	//configs Configs
+	metricsExporter *Exporter
+	handlers        = map[string]http.Handler{}
+	handlersMu      sync.Mutex
 )
 
 // NewExporter returns a new Oracle DB exporter for the provided DSN.
 func NewExporter() *Exporter {
-  e := Exporter{
-    	  duration: prometheus.NewGaugeVec(prometheus.GaugeOpts{
-    	    Namespace: namespace,
-    	    Subsystem: exporter,
-    	    Name:      "last_scrape_duration_seconds",
-    	    Help:      "Duration of the last scrape of metrics from Oracle DB.",
-    	  }, []string{}),
-    	  totalScrapes: prometheus.NewCounterVec(prometheus.CounterOpts{
-    	    Namespace: namespace,
-    	    Subsystem: exporter,
-    	    Name:      "scrapes_total",
-    	    Help:      "Total number of times Oracle DB was scraped for metrics.",
-    	  }, []string{"database","dbinstance"}),
-    	  scrapeErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
-    	    Namespace: namespace,
-    	    Subsystem: exporter,
-    	    Name:      "scrape_errors_total",
-    	    Help:      "Total number of times an error occured scraping a Oracle database.",
-    	  }, []string{"database","dbinstance"}),
-    	  error: prometheus.NewGaugeVec(prometheus.GaugeOpts{
-    	    Namespace: namespace,
-    	    Subsystem: exporter,
-    	    Name:      "last_scrape_error",
-    	    Help:      "Whether the last scrape of metrics from Oracle DB resulted in an error (1 for error, 0 for success).",
-    	  },[]string{"database","dbinstance"}),
-    	  sysmetric: prometheus.NewGaugeVec(prometheus.GaugeOpts{
-    	    Namespace: namespace,
-               Name:      "sysmetric",
-               Help:      "Gauge metric with read/write pysical IOPs/bytes (v$sysmetric).",
-          }, []string{"database", "dbinstance", "type"}),
-          waitclass: prometheus.NewGaugeVec(prometheus.GaugeOpts{
-               Namespace: namespace,
-               Name:      "waitclass",
-               Help:      "Gauge metric with Waitevents (v$waitclassmetric).",
-          }, []string{"database", "dbinstance", "type"}),
-          sysstat: prometheus.NewGaugeVec(prometheus.GaugeOpts{
-               Namespace: namespace,
-               Name:      "sysstat",
-               Help:      "Gauge metric with commits/rollbacks/parses (v$sysstat).",
-          }, []string{"database", "dbinstance", "type"}),
-          session: prometheus.NewGaugeVec(prometheus.GaugeOpts{
-               Namespace: namespace,
-               Name:      "session",
-               Help:      "Gauge metric user/system active/passive sessions (v$session).",
-          }, []string{"database", "dbinstance", "type", "state"}),
-          uptime: prometheus.NewGaugeVec(prometheus.GaugeOpts{
-               Namespace: namespace,
-               Name:      "uptime",
-               Help:      "Gauge metric with uptime in days of the Instance.",
-          }, []string{"database", "dbinstance"}),
-          tablespace: prometheus.NewGaugeVec(prometheus.GaugeOpts{
-               Namespace: namespace,
-               Name:      "tablespace",
-               Help:      "Gauge metric with total/free size of the Tablespaces.",
-          }, []string{"database", "dbinstance", "type", "name", "contents", "autoextend"}),
-          interconnect: prometheus.NewGaugeVec(prometheus.GaugeOpts{
-               Namespace: namespace,
-               Name:      "interconnect",
-               Help:      "Gauge metric with interconnect block transfers (v$sysstat).",
-          }, []string{"database", "dbinstance", "type"}),
-          recovery: prometheus.NewGaugeVec(prometheus.GaugeOpts{
-               Namespace: namespace,
-               Name:      "recovery",
-               Help:      "Gauge metric with percentage usage of FRA (v$recovery_file_dest).",
-          }, []string{"database", "dbinstance", "type"}),
-          redo: prometheus.NewGaugeVec(prometheus.GaugeOpts{
-               Namespace: namespace,
-               Name:      "redo",
-               Help:      "Gauge metric with Redo log switches over last 5 min (v$log_history).",
-          }, []string{"database", "dbinstance"}),
-          cache: prometheus.NewGaugeVec(prometheus.GaugeOpts{
-               Namespace: namespace,
-               Name:      "cachehitratio",
-               Help:      "Gauge metric witch Cache hit ratios (v$sysmetric).",
-          }, []string{"database", "dbinstance", "type"}),
-          up: prometheus.NewGaugeVec(prometheus.GaugeOpts{
-               Namespace: namespace,
-               Name:      "up",
-               Help:      "Whether the Oracle server is up.",
-          }, []string{"database", "dbinstance"}),
-          alertlog: prometheus.NewGaugeVec(prometheus.GaugeOpts{
-               Namespace: namespace,
-               Name:      "error",
-               Help:      "Oracle Errors occured during configured interval.",
-          }, []string{"database", "dbinstance", "code", "description", "ignore"}),
-          alertdate: prometheus.NewGaugeVec(prometheus.GaugeOpts{
-               Namespace: namespace,
-               Name:      "error_unix_seconds",
-               Help:      "Unixtime of Alertlog modified Date.",
-          }, []string{"database", "dbinstance"}),
-          services: prometheus.NewGaugeVec(prometheus.GaugeOpts{
-               Namespace: namespace,
-               Name:      "services",
-               Help:      "Active Oracle Services (v$active_services).",
-          }, []string{"database", "dbinstance", "name"}),
-          parameter: prometheus.NewGaugeVec(prometheus.GaugeOpts{
-               Namespace: namespace,
-               Name:      "parameter",
-               Help:      "oracle Configuration Parameters (v$parameter).",
-          }, []string{"database", "dbinstance", "name"}),
-          // query: prometheus.NewGaugeVec(prometheus.GaugeOpts{
-          //      Namespace: namespace,
-          //      Name:      "query",
-          //      Help:      "Self defined Queries from Configuration File.",
-          // }, []string{"database", "dbinstance", "name", "column", "row"}),
-          asmspace: prometheus.NewGaugeVec(prometheus.GaugeOpts{
-               Namespace: namespace,
-               Name:      "asmspace",
-               Help:      "Gauge metric with total/free size of the ASM Diskgroups.",
-          }, []string{"database", "dbinstance", "type", "name"}),
-          tablerows: prometheus.NewGaugeVec(prometheus.GaugeOpts{
-               Namespace: namespace,
-               Name:      "tablerows",
-               Help:      "Gauge metric with rows of all Tables.",
-          }, []string{"database", "dbinstance", "owner", "table_name", "tablespace"}),
-          tablebytes: prometheus.NewGaugeVec(prometheus.GaugeOpts{
-               Namespace: namespace,
-               Name:      "tablebytes",
-               Help:      "Gauge metric with bytes of all Tables.",
-          }, []string{"database", "dbinstance", "owner", "table_name"}),
-          indexbytes: prometheus.NewGaugeVec(prometheus.GaugeOpts{
-               Namespace: namespace,
-               Name:      "indexbytes",
-               Help:      "Gauge metric with bytes of all Indexes per Table.",
-          }, []string{"database", "dbinstance", "owner", "table_name"}),
-          lobbytes: prometheus.NewGaugeVec(prometheus.GaugeOpts{
-               Namespace: namespace,
-               Name:      "lobbytes",
-               Help:      "Gauge metric with bytes of all Lobs per Table.",
-          }, []string{"database", "dbinstance", "owner", "table_name"}),
-          custom: make(map[string]*prometheus.GaugeVec),
-     }
-     // add custom metrics
-     for _, conn := range config.Cfgs {
-          for _, query := range conn.Queries {
-               log.Debug("Add Query " + query.Name)
-               labels := []string{}
-               for _, label := range query.Labels {
-                    labels = append(labels, cleanName(label))
-               }
-               if  *pNoRownum == false {
-                    e.custom[query.Name] = prometheus.NewGaugeVec(prometheus.GaugeOpts{
-                         Namespace: namespace,
-                         Name:      "custom_" + cleanName(query.Name),
-                         Help:      query.Help,
-                    },
-                    append(labels, "metric", "database", "dbinstance", "rownum"))
-               } else {
-                    e.custom[query.Name] = prometheus.NewGaugeVec(prometheus.GaugeOpts{
-                         Namespace: namespace,
-                         Name:      "custom_" + cleanName(query.Name),
-                         Help:      query.Help,
-                    },
-                    append(labels, "metric", "database", "dbinstance"))
-                  }
-           }
-     }
-
-     return &e
-}
+	e := Exporter{
+		duration: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: exporter,
+			Name:      "last_scrape_duration_seconds",
+			Help:      "Duration of the last scrape of metrics from Oracle DB.",
+		}, []string{}),
+		totalScrapes: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: exporter,
+			Name:      "scrapes_total",
+			Help:      "Total number of times Oracle DB was scraped for metrics.",
+		}, []string{"database", "dbinstance"}),
+		scrapeErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: exporter,
+			Name:      "scrape_errors_total",
+			Help:      "Total number of times an error occured scraping a Oracle database.",
+		}, []string{"database", "dbinstance"}),
+		timeouts: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: exporter,
+			Name:      "collector_timeouts_total",
+			Help:      "Total number of times a collector was cancelled because its query timeout or the scrape deadline was reached.",
+		}, []string{"collector", "database", "dbinstance"}),
+		collectorTime: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: exporter,
+			Name:      "collector_duration_seconds",
+			Help:      "Duration of the last run of a collector.",
+		}, []string{"collector", "database", "dbinstance"}),
+		collectorUp: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: exporter,
+			Name:      "collector_success",
+			Help:      "Whether the last run of a collector succeeded (1 for success, 0 for error).",
+		}, []string{"collector", "database", "dbinstance"}),
+		lastSuccess: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: exporter,
+			Name:      "collector_last_success_timestamp_seconds",
+			Help:      "Unixtime of the last successful run of a collector.",
+		}, []string{"collector", "database", "dbinstance"}),
+		queryAge: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: exporter,
+			Name:      "custom_query_age_seconds",
+			Help:      "Age of the cached result of a custom query with an interval.",
+		}, []string{"query", "database", "dbinstance"}),
+		customErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: exporter,
+			Name:      "custom_metric_errors_total",
+			Help:      "Total number of samples of custom queries which were dropped because they are invalid, e.g. a label value which is not UTF-8.",
+		}, []string{"query", "database", "dbinstance"}),
+		connectError: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: exporter,
+			Name:      "connect_error",
+			Help:      "Whether the last connection attempt failed, by reason (wallet, tns, auth, timeout, other).",
+		}, []string{"database", "dbinstance", "reason"}),
+		error: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: exporter,
+			Name:      "last_scrape_error",
+			Help:      "Whether the last scrape of metrics from Oracle DB resulted in an error (1 for error, 0 for success).",
+		}, []string{"database", "dbinstance"}),
+		sysmetric: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "sysmetric",
+			Help:      "Gauge metric with the metrics of -collector.sysmetric.names (v$sysmetric).",
+		}, []string{"database", "dbinstance", "con_id", "pdb", "type"}),
+		waitclass: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "waitclass",
+			Help:      "Gauge metric with Waitevents (v$waitclassmetric).",
+		}, []string{"database", "dbinstance", "type"}),
+		sysstat: newCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "sysstat_total",
+			Help:      "Counter metric with the statistics of -collector.sysstat.names (v$sysstat).",
+		}, []string{"database", "dbinstance", "type"}),
+		session: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "session",
+			Help:      "Gauge metric user/system active/passive sessions (v$session).",
+		}, []string{"database", "dbinstance", "con_id", "pdb", "type", "state"}),
+		uptime: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "uptime",
+			Help:      "Gauge metric with uptime in days of the Instance.",
+		}, []string{"database", "dbinstance"}),
+		tablespace: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "tablespace",
+			Help:      "Gauge metric with total/free size of the Tablespaces.",
+		}, []string{"database", "dbinstance", "con_id", "pdb", "type", "name", "contents", "autoextend"}),
+		interconnect: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "interconnect",
+			Help:      "Gauge metric with interconnect block transfers (v$sysstat).",
+		}, []string{"database", "dbinstance", "type"}),
+		recovery: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "recovery",
+			Help:      "Gauge metric with percentage usage of FRA (v$recovery_file_dest).",
+		}, []string{"database", "dbinstance", "type"}),
+		redo: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "redo",
+			Help:      "Gauge metric with Redo log switches over last 5 min (v$log_history).",
+		}, []string{"database", "dbinstance"}),
+		cache: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "cachehitratio",
+			Help:      "Gauge metric witch Cache hit ratios (v$sysmetric).",
+		}, []string{"database", "dbinstance", "type"}),
+		up: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "up",
+			Help:      "Whether the Oracle server is up.",
+		}, []string{"database", "dbinstance"}),
+		alertlog: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "error",
+			Help:      "Oracle Errors occured during configured interval.",
+		}, []string{"database", "dbinstance", "code", "description", "ignore"}),
+		alertdate: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "error_unix_seconds",
+			Help:      "Unixtime of Alertlog modified Date.",
+		}, []string{"database", "dbinstance"}),
+		services: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "services",
+			Help:      "Active Oracle Services (v$active_services).",
+		}, []string{"database", "dbinstance", "name"}),
+		parameter: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "parameter",
+			Help:      "oracle Configuration Parameters with a numeric value (v$parameter).",
+		}, []string{"database", "dbinstance", "name", "isdefault", "ismodified"}),
+		parameterInfo: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "parameter_info",
+			Help:      "oracle Configuration Parameters with a string or boolean value in the label value, always 1 (v$parameter).",
+		}, []string{"database", "dbinstance", "name", "value", "isdefault", "ismodified"}),
+		// query: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		//      Namespace: namespace,
+		//      Name:      "query",
+		//      Help:      "Self defined Queries from Configuration File.",
+		// }, []string{"database", "dbinstance", "name", "column", "row"}),
+		asmspace: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "asmspace",
+			Help:      "Gauge metric with total/free size of the ASM Diskgroups.",
+		}, []string{"database", "dbinstance", "type", "name"}),
+		tablerows: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "tablerows",
+			Help:      "Gauge metric with rows of all Tables.",
+		}, []string{"database", "dbinstance", "con_id", "pdb", "owner", "table_name", "tablespace"}),
+		tablebytes: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "tablebytes",
+			Help:      "Gauge metric with bytes of all Tables.",
+		}, []string{"database", "dbinstance", "con_id", "pdb", "owner", "table_name"}),
+		indexbytes: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "indexbytes",
+			Help:      "Gauge metric with bytes of all Indexes per Table.",
+		}, []string{"database", "dbinstance", "con_id", "pdb", "owner", "table_name"}),
+		lobbytes: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "lobbytes",
+			Help:      "Gauge metric with bytes of all Lobs per Table.",
+		}, []string{"database", "dbinstance", "con_id", "pdb", "owner", "table_name"}),
+		pdbOpenMode: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "pdb",
+			Name:      "open_mode",
+			Help:      "Open mode of the PDB, 1 for the current mode (v$pdbs).",
+		}, []string{"database", "dbinstance", "pdb", "mode"}),
+		pdbRestricted: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "pdb",
+			Name:      "restricted",
+			Help:      "Whether the PDB is open in restricted mode (v$pdbs).",
+		}, []string{"database", "dbinstance", "pdb"}),
+		pdbSize: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "pdb",
+			Name:      "size_bytes",
+			Help:      "Total size of the PDB, 0 if it is not open (v$pdbs).",
+		}, []string{"database", "dbinstance", "pdb"}),
+		pdbRecovery: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "pdb",
+			Name:      "recovery_enabled",
+			Help:      "Whether the PDB is recovered on a standby, 0 if its recovery_status is DISABLED (v$pdbs).",
+		}, []string{"database", "dbinstance", "pdb"}),
+		pdbResource: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "pdb",
+			Name:      "resource",
+			Help:      "Gauge metric with CPU and IO of the PDB in the last minute (v$rsrcpdbmetric).",
+		}, []string{"database", "dbinstance", "pdb", "type"}),
+		dataguardRole: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "dataguard",
+			Name:      "database_role",
+			Help:      "Role of the database, 1 for the current role (v$database).",
+		}, []string{"database", "dbinstance", "role"}),
+		dataguardProtection: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "dataguard",
+			Name:      "protection_mode",
+			Help:      "Protection mode of the database, 1 for the current mode (v$database).",
+		}, []string{"database", "dbinstance", "mode"}),
+		dataguardSwitchover: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "dataguard",
+			Name:      "switchover_status",
+			Help:      "Switchover status of the database, 1 for the current status (v$database).",
+		}, []string{"database", "dbinstance", "status"}),
+		dataguardLag: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "dataguard",
+			Name:      "lag_seconds",
+			Help:      "Transport and apply lag of the standby (v$dataguard_stats).",
+		}, []string{"database", "dbinstance", "type"}),
+		dataguardApplyFinish: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "dataguard",
+			Name:      "apply_finish_seconds",
+			Help:      "Estimated time until the standby has applied all received redo (v$dataguard_stats).",
+		}, []string{"database", "dbinstance"}),
+		archiveDestStatus: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "dataguard",
+			Name:      "archive_dest_status",
+			Help:      "Status of the archive destination, 1 for the current status (v$archive_dest_status).",
+		}, []string{"database", "dbinstance", "dest_name", "status"}),
+		archiveDestError: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "dataguard",
+			Name:      "archive_dest_error",
+			Help:      "Whether the archive destination has an error (v$archive_dest_status).",
+		}, []string{"database", "dbinstance", "dest_name"}),
+		redoSwitches: newCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "redo",
+			Name:      "log_switches_total",
+			Help:      "Redo log switches of the thread, the sequence# of its current log (v$log).",
+		}, []string{"database", "dbinstance", "thread"}),
+		redoLogStatus: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "redo",
+			Name:      "log_status",
+			Help:      "Status of the online log group, 1 for the current status (v$log).",
+		}, []string{"database", "dbinstance", "thread", "group", "status"}),
+		redoLogArchived: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "redo",
+			Name:      "log_archived",
+			Help:      "Whether the online log group is archived (v$log).",
+		}, []string{"database", "dbinstance", "thread", "group"}),
+		archivedFiles: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "archive",
+			Name:      "logs",
+			Help:      "Logs archived within -collector.archive.window (v$archived_log).",
+		}, []string{"database", "dbinstance", "thread"}),
+		archivedBytes: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "archive",
+			Name:      "bytes",
+			Help:      "Bytes of the logs archived within -collector.archive.window (v$archived_log).",
+		}, []string{"database", "dbinstance", "thread"}),
+		archiveDest: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "archive",
+			Name:      "dest_status",
+			Help:      "Status of the archive destination, 1 for the current status (v$archive_dest).",
+		}, []string{"database", "dbinstance", "dest_name", "destination", "status"}),
+		archiveDestSpace: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "archive",
+			Name:      "dest_bytes",
+			Help:      "Limit, used and free bytes of the archive destination, of the FRA for USE_DB_RECOVERY_FILE_DEST (v$archive_dest, v$recovery_file_dest).",
+		}, []string{"database", "dbinstance", "dest_name", "type"}),
+		asmDiskgroupState: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "asm",
+			Name:      "diskgroup_state",
+			Help:      "State of the diskgroup, 1 for the current state (v$asm_diskgroup_stat).",
+		}, []string{"database", "dbinstance", "diskgroup", "redundancy", "state"}),
+		asmDiskgroupSpace: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "asm",
+			Name:      "diskgroup_bytes",
+			Help:      "Total, free, usable_file and required_mirror_free bytes of the diskgroup (v$asm_diskgroup_stat).",
+		}, []string{"database", "dbinstance", "diskgroup", "redundancy", "type"}),
+		asmOfflineDisks: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "asm",
+			Name:      "diskgroup_offline_disks",
+			Help:      "Offline disks of the diskgroup (v$asm_diskgroup_stat).",
+		}, []string{"database", "dbinstance", "diskgroup", "redundancy"}),
+		asmDiskMountStatus: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "asm",
+			Name:      "disk_mount_status",
+			Help:      "Mount status of the disk, 1 for the current status (v$asm_disk_stat).",
+		}, []string{"database", "dbinstance", "diskgroup", "disk", "status"}),
+		asmDiskModeStatus: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "asm",
+			Name:      "disk_mode_status",
+			Help:      "Mode status of the disk, 1 for the current status (v$asm_disk_stat).",
+		}, []string{"database", "dbinstance", "diskgroup", "disk", "status"}),
+		asmDiskState: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "asm",
+			Name:      "disk_state",
+			Help:      "State of the disk, 1 for the current state (v$asm_disk_stat).",
+		}, []string{"database", "dbinstance", "diskgroup", "disk", "state"}),
+		asmDiskErrors: newCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "asm",
+			Name:      "disk_errors_total",
+			Help:      "Read and write errors of the disk (v$asm_disk_stat).",
+		}, []string{"database", "dbinstance", "diskgroup", "disk", "type"}),
+		asmOperationProgress: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "asm",
+			Name:      "operation_progress_ratio",
+			Help:      "Done part of the estimated work of a running operation like a rebalance (v$asm_operation).",
+		}, []string{"database", "dbinstance", "diskgroup", "operation", "pass", "state"}),
+		asmOperationRemaining: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "asm",
+			Name:      "operation_remaining_seconds",
+			Help:      "Estimated time until a running operation is done (v$asm_operation).",
+		}, []string{"database", "dbinstance", "diskgroup", "operation", "pass", "state"}),
+		asmOperationPower: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "asm",
+			Name:      "operation_power",
+			Help:      "Power of a running operation (v$asm_operation).",
+		}, []string{"database", "dbinstance", "diskgroup", "operation", "pass", "state"}),
+		waitEventWaits: newCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "wait_event",
+			Name:      "waits_total",
+			Help:      "Waits for the event since startup (v$system_event).",
+		}, []string{"database", "dbinstance", "event", "wait_class"}),
+		waitEventTimeouts: newCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "wait_event",
+			Name:      "timeouts_total",
+			Help:      "Waits for the event which timed out since startup (v$system_event).",
+		}, []string{"database", "dbinstance", "event", "wait_class"}),
+		waitEventTime: newCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "wait_event",
+			Name:      "time_waited_seconds_total",
+			Help:      "Time waited for the event since startup (v$system_event).",
+		}, []string{"database", "dbinstance", "event", "wait_class"}),
+		resourceCurrent: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "resource",
+			Name:      "current_utilization",
+			Help:      "Current utilization of the resource (v$resource_limit).",
+		}, []string{"database", "dbinstance", "resource"}),
+		resourceMax: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "resource",
+			Name:      "max_utilization",
+			Help:      "Maximum utilization of the resource since startup (v$resource_limit).",
+		}, []string{"database", "dbinstance", "resource"}),
+		resourceLimit: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "resource",
+			Name:      "limit_value",
+			Help:      "Limit of the resource, no sample if it is UNLIMITED (v$resource_limit).",
+		}, []string{"database", "dbinstance", "resource"}),
+		custom: make(map[string]*prometheus.Desc),
+	}
+	// add custom metrics
+	for _, conn := range currentConfig().Cfgs {
+		for _, query := range conn.Queries {
+			log.Debug("Add Query " + query.Name)
+			for _, metric := range query.Metrics {
+				e.custom[customFQName(query, metric)] = customDesc(query, metric)
+			}
+		}
+	}
 
-// ScrapeCustomQueries collects metrics from self defined queries from configuration file.
-func (e *Exporter) ScrapeCustomQueries(pNoRownum bool) {
-     var (
-          rows *sql.Rows
-          err  error
-     )
-     for _, config := range e.configs {
-        db := config.db
-
-         if db != nil {
-               for _, query := range config.Queries {
-                    log.Debug("execute "+ query.Name)
-                    rows, err = db.Query(query.Sql)
-                    if err != nil {
-                         log.Error("Error in Query '" +  query.Sql + "': ")
-                    fmt.Print(err)
-                         continue
-                    }
-
-                    cols, _ := rows.Columns()
-                    vals := make([]interface{}, len(cols))
-
-                    defer rows.Close()
-                    var rownum int = 1
-
-                    for rows.Next() {
-                         for i := range cols {
-                              vals[i] = &vals[i]
-                         }
-
-                         err = rows.Scan(vals...)
-                         if err != nil {
-                              break
-                         }
-
-                    MetricLoop:
-                         for _, metric := range query.Metrics {
-                              metricColumnIndex := -1
-                              for i, col := range cols {
-                                   if cleanName(metric) == cleanName(col) {
-                                        log.Debugln("Metric column '" + metric + "' found")
-                                        metricColumnIndex = i
-                                        break
-                                   }
-                              }
-
-                              if metricColumnIndex == -1 {
-                                   log.Errorln("Metric column '" + metric + "' not found")
-                                   continue MetricLoop
-                              }
-
-                              if metricValue, ok := vals[metricColumnIndex].(float64); ok {
-                                   promLabels := prometheus.Labels{}
-                                   promLabels["database"] = config.Database
-                                   promLabels["dbinstance"] = config.Instance
-                                   promLabels["metric"] = metric
-                                   if pNoRownum == false {
-                                        promLabels["rownum"] = strconv.Itoa(rownum)
-                                   }
-                              LebelLoop:
-                                   for _, label := range query.Labels {
-                                        labelColumnIndex := -1
-                                        for i, col := range cols {
-                                             if cleanName(label) == cleanName(col) {
-                                                  log.Debugln("Label column '" + label + "' found")
-                                                  labelColumnIndex = i
-                                                  break
-                                             }
-                                        }
-
-                                        if labelColumnIndex == -1 {
-                                             log.Errorln("Label column '" + label + "' not found")
-                                             break LebelLoop
-                                        }
-
-                                        if a, ok := vals[labelColumnIndex].(string); ok {
-                                             promLabels[cleanName(label)] = a
-                                        } else if b, ok := vals[labelColumnIndex].(float64); ok {
-                                             // if value is integer
-                                             if b == float64(int64(b)) {
-                                                  promLabels[cleanName(label)] = strconv.Itoa(int(b))
-                                             } else {
-                                                  promLabels[cleanName(label)] = strconv.FormatFloat(b, 'e', -1, 64)
-                                             }
-                                        }
-                                   }
-                                   e.custom[query.Name].With(promLabels).Set(metricValue)
-                              }
-                         }
-
-                         rownum++
-                    }
-               }
-		  }
-	 }
+	return &e
 }
 
 // ScrapeQuery collects metrics from self defined queries from configuration file.
@@ -395,779 +589,633 @@ func (e *Exporter) ScrapeCustomQueries(pNoRownum bool) {
 //      }
 // }
 
-// ScrapeParameters collects metrics from the v$parameters view.
-func (e *Exporter) ScrapeParameter() {
-     var (
-          rows *sql.Rows
-          err  error
-  )
-
-  for _, config := range e.configs {
-	  db := config.db
-
-    //num  metric_name
-    //43  sessions
-    if db != nil {
-      rows, err = db.Query(`select name,value from v$parameter WHERE num=43`)
-      if err != nil {
-        fmt.Println(err)
-        return
-      }
-
-      defer rows.Close()
-
-      for rows.Next() {
-        var name string
-        var value float64
-        if err := rows.Scan(&name,&value); err != nil {
-          break
-        }
-        name = cleanName(name)
-        e.parameter.WithLabelValues(config.Database,config.Instance,name).Set(value)
-      }
-	}
-  }	
-}
-
 // ScrapeServices collects metrics from the v$active_services view.
-func (e *Exporter) ScrapeServices() {
-     var (
-          rows *sql.Rows
-    err  error
-  )
-
-  for _, config := range e.configs {
+func (e *Exporter) ScrapeServices(ctx context.Context, config *Config) error {
 	db := config.db
 
-    if db != nil {
-      rows, err = db.Query(`select name from v$active_services`)
-      if err != nil {
-            fmt.Println(err)
-            return
-      }
-      defer rows.Close()
-      for rows.Next() {
-        var name string
-        if err := rows.Scan(&name); err != nil {
-          break
-        }
-        name = cleanName(name)
-        e.services.WithLabelValues(config.Database,config.Instance,name).Set(1)
-      }
+	rows, err := db.QueryContext(ctx, `select name from v$active_services`)
+	if err != nil {
+		return err
 	}
-  }	
+	defer rows.Close()
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return err
+		}
+		name = cleanName(name)
+		e.services.WithLabelValues(config.Database, config.Instance, name).Set(1)
+	}
+	return rows.Err()
 }
 
-// ScrapeCache collects session metrics from the v$sysmetrics view.
-func (e *Exporter) ScrapeCache() {
-     var (
-          rows *sql.Rows
-          err  error
-  )
-
-  for _, config := range e.configs {
+// ScrapeRedo collects the log switches from v$log_history and the online logs from v$log.
+func (e *Exporter) ScrapeRedo(ctx context.Context, config *Config) error {
 	db := config.db
 
-    //metric_id  metric_name
-    //2000    Buffer Cache Hit Ratio
-    //2050    Cursor Cache Hit Ratio
-    //2112    Library Cache Hit Ratio
-    //2110    Row Cache Hit Ratio
-  
-    if db != nil {
-      rows, err = db.Query(`select metric_name,value
-                                 from v$sysmetric
-                                 where group_id=2 and metric_id in (2000,2050,2112,2110)`)
-      if err != nil {
-            fmt.Println(err)
-            return
-      }
-      defer rows.Close()
-      for rows.Next() {
-        var name string
-        var value float64
-        if err := rows.Scan(&name, &value); err != nil {
-          break
-        }
-        name = cleanName(name)
-        e.cache.WithLabelValues(config.Database,config.Instance,name).Set(value)
-      }
+	rows, err := db.QueryContext(ctx, `select count(*) from v$log_history where first_time > sysdate - 1/24/12`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var value float64
+		if err := rows.Scan(&value); err != nil {
+			return err
+		}
+		e.redo.WithLabelValues(config.Database, config.Instance).Set(value)
+	}
+	if err := rows.Err(); err != nil {
+		return err
 	}
-  }	
-}
-
-// ScrapeRecovery collects tablespace metrics
-func (e *Exporter) ScrapeRedo() {
-     var (
-          rows *sql.Rows
-          err  error
-  )
-
-  for _, config := range e.configs {
-	db := config.db
 
-    if db != nil {
-      rows, err = db.Query(`select count(*) from v$log_history where first_time > sysdate - 1/24/12`)
-      if err != nil {
-            fmt.Println(err)
-            return
-      }
-      defer rows.Close()
-      for rows.Next() {
-        var value float64
-        if err := rows.Scan(&value); err != nil {
-          break
-        }
-        e.redo.WithLabelValues(config.Database,config.Instance).Set(value)
-      }
+	// every switch increases the sequence# of the thread, so it counts the switches since the last RESETLOGS
+	rows, err = db.QueryContext(ctx, `select thread#, group#, sequence#, status, archived from v$log`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	switches := map[string]float64{}
+	for rows.Next() {
+		var thread string
+		var group string
+		var sequence float64
+		var status string
+		var archived string
+		if err := rows.Scan(&thread, &group, &sequence, &status, &archived); err != nil {
+			return err
+		}
+		if sequence > switches[thread] {
+			switches[thread] = sequence
+		}
+		e.redoLogStatus.WithLabelValues(config.Database, config.Instance, thread, group, status).Set(1)
+		e.redoLogArchived.WithLabelValues(config.Database, config.Instance, thread, group).Set(boolValue(archived == "YES"))
 	}
-  }
+	for thread, sequence := range switches {
+		e.redoSwitches.WithLabelValues(config.Database, config.Instance, thread).Set(sequence)
+	}
+	return rows.Err()
 }
 
 // ScrapeRecovery collects tablespace metrics
-func (e *Exporter) ScrapeRecovery() {
-  var (
-    rows *sql.Rows
-    err  error
-  )
-
-  for _, config := range e.configs {
+func (e *Exporter) ScrapeRecovery(ctx context.Context, config *Config) error {
 	db := config.db
 
-  if db != nil {
-    rows, err = db.Query(`SELECT sum(percent_space_used) , sum(percent_space_reclaimable)
+	rows, err := db.QueryContext(ctx, `SELECT sum(percent_space_used) , sum(percent_space_reclaimable)
                              from V$FLASH_RECOVERY_AREA_USAGE`)
-    if err != nil {
-          fmt.Println(err)
-          return
-    }
-    defer rows.Close()
-    for rows.Next() {
-      var used float64
-      var recl float64
-      if err := rows.Scan(&used, &recl); err != nil {
-        break
-      }
-      e.recovery.WithLabelValues(config.Database,config.Instance,"percent_space_used").Set(used)
-      e.recovery.WithLabelValues(config.Database,config.Instance,"percent_space_reclaimable").Set(recl)
-    }
-  }
- }
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var used float64
+		var recl float64
+		if err := rows.Scan(&used, &recl); err != nil {
+			return err
+		}
+		e.recovery.WithLabelValues(config.Database, config.Instance, "percent_space_used").Set(used)
+		e.recovery.WithLabelValues(config.Database, config.Instance, "percent_space_reclaimable").Set(recl)
+	}
+	return rows.Err()
 }
 
 // ScrapeTablespaces collects tablespace metrics
-func (e *Exporter) ScrapeInterconnect() {
-     var (
-          rows *sql.Rows
-          err  error
-  )
-
-  for _, config := range e.configs {
+func (e *Exporter) ScrapeInterconnect(ctx context.Context, config *Config) error {
 	db := config.db
 
-    if db != nil {
-      rows, err = db.Query(`SELECT name, value
+	rows, err := db.QueryContext(ctx, `SELECT name, value
                                  FROM V$SYSSTAT
                                  WHERE name in ('gc cr blocks served','gc cr blocks flushed','gc cr blocks received')`)
-      if err != nil {
-            fmt.Println(err)
-            return
-      }
-      defer rows.Close()
-      for rows.Next() {
-        var name string
-        var value float64
-        if err := rows.Scan(&name, &value); err != nil {
-          break
-        }
-        name = cleanName(name)
-        e.interconnect.WithLabelValues(config.Database,config.Instance,name).Set(value)
-      }
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name string
+		var value float64
+		if err := rows.Scan(&name, &value); err != nil {
+			return err
+		}
+		name = cleanName(name)
+		e.interconnect.WithLabelValues(config.Database, config.Instance, name).Set(value)
 	}
-  }	
+	return rows.Err()
 }
 
 // ScrapeAsmspace collects ASM metrics
-func (e *Exporter) ScrapeAsmspace() {
-     var (
-          rows *sql.Rows
-          err  error
-  )
-
-  for _, config := range e.configs {
+func (e *Exporter) ScrapeAsmspace(ctx context.Context, config *Config) error {
 	db := config.db
 
-    if db != nil {
-      rows, err = db.Query(`SELECT g.name, sum(d.total_mb), sum(d.free_mb)
+	rows, err := db.QueryContext(ctx, `SELECT g.name, sum(d.total_mb), sum(d.free_mb)
                                   FROM v$asm_disk d, v$asm_diskgroup g
                                  WHERE  d.group_number = g.group_number
                                   AND  d.header_status = 'MEMBER'
                                  GROUP by  g.name,  g.group_number`)
-      if err != nil {
-            fmt.Println(err)
-            return
-      }
-      defer rows.Close()
-      for rows.Next() {
-        var name string
-        var tsize float64
-        var tfree float64
-        if err := rows.Scan(&name, &tsize, &tfree); err != nil {
-          break
-        }
-        e.asmspace.WithLabelValues(config.Database,config.Instance,"total",name).Set(tsize)
-        e.asmspace.WithLabelValues(config.Database,config.Instance,"free",name).Set(tfree)
-        e.asmspace.WithLabelValues(config.Database,config.Instance,"used",name).Set(tsize-tfree)
-      }
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name string
+		var tsize float64
+		var tfree float64
+		if err := rows.Scan(&name, &tsize, &tfree); err != nil {
+			return err
+		}
+		e.asmspace.WithLabelValues(config.Database, config.Instance, "total", name).Set(tsize)
+		e.asmspace.WithLabelValues(config.Database, config.Instance, "free", name).Set(tfree)
+		e.asmspace.WithLabelValues(config.Database, config.Instance, "used", name).Set(tsize - tfree)
 	}
-  }	
+	return rows.Err()
 }
 
 // ScrapeTablespaces collects tablespace metrics
-func (e *Exporter) ScrapeTablespace() {
-     var (
-          rows *sql.Rows
-          err  error
-  )
-
-  for _, config := range e.configs {
+func (e *Exporter) ScrapeTablespace(ctx context.Context, config *Config) error {
 	db := config.db
 
-    if db != nil {
-      rows, err = db.Query(`WITH
+	query := `WITH
                                    getsize AS (SELECT tablespace_name, autoextensible, SUM(bytes) tsize
                                                FROM dba_data_files GROUP BY tablespace_name, autoextensible),
                                    getfree as (SELECT tablespace_name, contents, SUM(blocks*block_size) tfree
                                                FROM DBA_LMT_FREE_SPACE a, v$tablespace b, dba_tablespaces c
                                                WHERE a.TABLESPACE_ID= b.ts# and b.name=c.tablespace_name
                                                GROUP BY tablespace_name,contents)
-                                 SELECT a.tablespace_name, b.contents, a.tsize,  b.tfree, a.autoextensible autoextend
+                                 SELECT 0, NULL, a.tablespace_name, b.contents, a.tsize,  b.tfree, a.autoextensible autoextend
                                  FROM GETSIZE a, GETFREE b
                                  WHERE a.tablespace_name = b.tablespace_name
                                  UNION
-                                 SELECT tablespace_name, 'TEMPORARY', sum(tablespace_size), sum(free_space), 'NO'
+                                 SELECT 0, NULL, tablespace_name, 'TEMPORARY', sum(tablespace_size), sum(free_space), 'NO'
                                  FROM dba_temp_free_space
-                                 GROUP BY tablespace_name`)
-      if err != nil {
-            fmt.Println(err)
-            return
-      }
-      defer rows.Close()
-      for rows.Next() {
-        var name string
-        var contents string
-        var tsize float64
-        var tfree float64
-        var auto string
-        if err := rows.Scan(&name, &contents, &tsize, &tfree, &auto); err != nil {
-          break
-        }
-        e.tablespace.WithLabelValues(config.Database,config.Instance,"total",name,contents,auto).Set(tsize)
-        e.tablespace.WithLabelValues(config.Database,config.Instance,"free",name,contents,auto).Set(tfree)
-        e.tablespace.WithLabelValues(config.Database,config.Instance,"used",name,contents,auto).Set(tsize-tfree)
-      }
+                                 GROUP BY tablespace_name`
+	if config.cdb {
+		query = `WITH
+                                   getsize AS (SELECT con_id, tablespace_name, autoextensible, SUM(bytes) tsize
+                                               FROM cdb_data_files GROUP BY con_id, tablespace_name, autoextensible),
+                                   getfree as (SELECT c.con_id, c.tablespace_name, c.contents, NVL(SUM(f.bytes),0) tfree
+                                               FROM cdb_tablespaces c LEFT JOIN cdb_free_space f
+                                               ON f.con_id = c.con_id AND f.tablespace_name = c.tablespace_name
+                                               GROUP BY c.con_id, c.tablespace_name, c.contents)
+                                 SELECT a.con_id, p.name, a.tablespace_name, b.contents, a.tsize,  b.tfree, a.autoextensible autoextend
+                                 FROM GETSIZE a JOIN GETFREE b ON a.con_id = b.con_id AND a.tablespace_name = b.tablespace_name
+                                 LEFT JOIN v$containers p ON p.con_id = a.con_id
+                                 UNION
+                                 SELECT t.con_id, p.name, t.tablespace_name, 'TEMPORARY', sum(t.tablespace_size), sum(t.free_space), 'NO'
+                                 FROM cdb_temp_free_space t LEFT JOIN v$containers p ON p.con_id = t.con_id
+                                 GROUP BY t.con_id, p.name, t.tablespace_name`
+	}
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return err
 	}
-  }	
+	defer rows.Close()
+	for rows.Next() {
+		var conID string
+		var pdb sql.NullString
+		var name string
+		var contents string
+		var tsize float64
+		var tfree float64
+		var auto string
+		if err := rows.Scan(&conID, &pdb, &name, &contents, &tsize, &tfree, &auto); err != nil {
+			return err
+		}
+		e.tablespace.WithLabelValues(config.Database, config.Instance, conID, pdb.String, "total", name, contents, auto).Set(tsize)
+		e.tablespace.WithLabelValues(config.Database, config.Instance, conID, pdb.String, "free", name, contents, auto).Set(tfree)
+		e.tablespace.WithLabelValues(config.Database, config.Instance, conID, pdb.String, "used", name, contents, auto).Set(tsize - tfree)
+	}
+	return rows.Err()
 }
 
 // ScrapeSessions collects session metrics from the v$session view.
-func (e *Exporter) ScrapeSession() {
-     var (
-          rows *sql.Rows
-          err  error
-  )
-
-  for _, config := range e.configs {
+func (e *Exporter) ScrapeSession(ctx context.Context, config *Config) error {
 	db := config.db
 
-    if db != nil {
-      rows, err = db.Query(`SELECT decode(username,NULL,'SYSTEM','SYS','SYSTEM','USER'), status,count(*)
+	query := `SELECT 0, NULL, decode(username,NULL,'SYSTEM','SYS','SYSTEM','USER'), status,count(*)
                                  FROM v$session
-                                 GROUP BY decode(username,NULL,'SYSTEM','SYS','SYSTEM','USER'),status`)
-      if err != nil {
-            fmt.Println(err)
-            return
-      }
-      defer rows.Close()
-      for rows.Next() {
-        var user string
-        var status string
-        var value float64
-        if err := rows.Scan(&user, &status, &value); err != nil {
-            log.Errorln(fmt.Print(err))
-          break
-        }
-        e.session.WithLabelValues(config.Database,config.Instance,user,status).Set(value)
-      }
+                                 GROUP BY decode(username,NULL,'SYSTEM','SYS','SYSTEM','USER'),status`
+	if config.cdb {
+		query = `SELECT s.con_id, c.name, decode(s.username,NULL,'SYSTEM','SYS','SYSTEM','USER'), s.status, count(*)
+                                 FROM v$session s LEFT JOIN v$containers c ON c.con_id = s.con_id
+                                 GROUP BY s.con_id, c.name, decode(s.username,NULL,'SYSTEM','SYS','SYSTEM','USER'), s.status`
 	}
-  }
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var conID string
+		var pdb sql.NullString
+		var user string
+		var status string
+		var value float64
+		if err := rows.Scan(&conID, &pdb, &user, &status, &value); err != nil {
+			return err
+		}
+		e.session.WithLabelValues(config.Database, config.Instance, conID, pdb.String, user, status).Set(value)
+	}
+	return rows.Err()
 }
 
 // ScrapeUptime Instance uptime
-func (e *Exporter) ScrapeUptime() {
-  var uptime float64
+func (e *Exporter) ScrapeUptime(ctx context.Context, config *Config) error {
+	var uptime float64
 
-  for _, config := range e.configs {
 	db := config.db
 
-    if db != nil {
-      rows, err := db.Query("select sysdate-startup_time from v$instance")
-      if err != nil {
-            fmt.Println(err)
-            return
-      }
-  
-      defer rows.Close()
-      rows.Next()
-      err = rows.Scan(&uptime)
-      if err == nil {
-        e.uptime.WithLabelValues(config.Database,config.Instance).Set(uptime)
-      } else {
-            fmt.Println(err)
-       }
+	rows, err := db.QueryContext(ctx, "select sysdate-startup_time from v$instance")
+	if err != nil {
+		return err
 	}
-  }
-}
 
-// ScrapeSysstat collects activity metrics from the v$sysstat view.
-func (e *Exporter) ScrapeSysstat() {
-     var (
-          rows *sql.Rows
-          err  error
-  )
-
-  for _, config := range e.configs {
-	db := config.db
-
-    if db != nil {
-      rows, err = db.Query(`SELECT name, value FROM v$sysstat
-                                      WHERE statistic# in (6,7,1084,1089)`)
-      if err != nil {
-            fmt.Println(err)
-            return
-      }
-      defer rows.Close()
-      for rows.Next() {
-        var name string
-        var value float64
-        if err := rows.Scan(&name, &value); err != nil {
-          break
-        }
-        name = cleanName(name)
-        e.sysstat.WithLabelValues(config.Database,config.Instance,name).Set(value)
-      }
+	defer rows.Close()
+	rows.Next()
+	err = rows.Scan(&uptime)
+	if err != nil {
+		return err
 	}
-  }
+	e.uptime.WithLabelValues(config.Database, config.Instance).Set(uptime)
+	return nil
 }
 
 // ScrapeWaitTime collects wait time metrics from the v$waitclassmetric view.
-func (e *Exporter) ScrapeWaitclass() {
-     var (
-          rows *sql.Rows
-          err  error
-  )
-
-  for _, config := range e.configs {
+func (e *Exporter) ScrapeWaitclass(ctx context.Context, config *Config) error {
 	db := config.db
 
-    if db != nil {
-      rows, err = db.Query(`SELECT n.wait_class, round(m.time_waited/m.INTSIZE_CSEC,3)
+	// there are no wait class metrics per container, in a CDB they are the
+	// values of the whole CDB
+	rows, err := db.QueryContext(ctx, `SELECT n.wait_class, round(m.time_waited/m.INTSIZE_CSEC,3)
                                     FROM v$waitclassmetric  m, v$system_wait_class n
                                     WHERE m.wait_class_id=n.wait_class_id and n.wait_class != 'Idle'`)
-      if err != nil {
-            fmt.Println(err)
-            return
-      }
-      defer rows.Close()
-      for rows.Next() {
-        var name string
-        var value float64
-        if err := rows.Scan(&name, &value); err != nil {
-          break
-        }
-        name = cleanName(name)
-        e.waitclass.WithLabelValues(config.Database,config.Instance,name).Set(value)
-      }
+	if err != nil {
+		return err
 	}
-  }
-}
-
-// ScrapeSysmetrics collects session metrics from the v$sysmetrics view.
-func (e *Exporter) ScrapeSysmetric() {
-     var (
-          rows *sql.Rows
-          err  error
-  )
-
-  for _, config := range e.configs {
-	db := config.db
-
-    //metric_id  metric_name
-    //2092    Physical Read Total IO Requests Per Sec
-    //2093    Physical Read Total Bytes Per Sec
-    //2100    Physical Write Total IO Requests Per Sec
-    //2124    Physical Write Total Bytes Per Sec
-    if db != nil {
-      rows, err = db.Query("select metric_name,value from v$sysmetric where metric_id in (2092,2093,2124,2100)")
-      if err != nil {
-            fmt.Println(err)
-            return
-      }
-      defer rows.Close()
-      for rows.Next() {
-        var name string
-        var value float64
-        if err := rows.Scan(&name, &value); err != nil {
-          break
-        }
-        name = cleanName(name)
-        e.sysmetric.WithLabelValues(config.Database,config.Instance,name).Set(value)
-      }
+	defer rows.Close()
+	for rows.Next() {
+		var name string
+		var value float64
+		if err := rows.Scan(&name, &value); err != nil {
+			return err
+		}
+		name = cleanName(name)
+		e.waitclass.WithLabelValues(config.Database, config.Instance, name).Set(value)
 	}
-  }
+	return rows.Err()
 }
 
 // ScrapeTablerows collects bytes from dba_tables view.
-func (e *Exporter) ScrapeTablerows() {
-     var (
-          rows *sql.Rows
-          err  error
-     )
-	 for _, config := range e.configs {
-		db := config.db
-	    if db != nil {
-           rows, err = db.Query(`select owner,table_name, tablespace_name, num_rows
+func (e *Exporter) ScrapeTablerows(ctx context.Context, config *Config) error {
+	db := config.db
+	query := `select 0, NULL, owner,table_name, tablespace_name, num_rows
                              from dba_tables
-                             where owner not like '%SYS%' and num_rows is not null`)
-           if err != nil {
-                fmt.Println(err)
-                return
-           }
-           defer rows.Close()
-           for rows.Next() {
-                var owner string
-                var name string
-                var space string
-                var value float64
-                if err := rows.Scan(&owner, &name, &space, &value); err != nil {
-                     break
-                }
-                name = cleanName(name)
-                e.tablerows.WithLabelValues(config.Database, config.Instance, owner, name, space).Set(value)
-           }
+                             where owner not like '%SYS%' and num_rows is not null`
+	if config.cdb {
+		query = `select t.con_id, c.name, t.owner, t.table_name, t.tablespace_name, t.num_rows
+                             from cdb_tables t LEFT JOIN v$containers c ON c.con_id = t.con_id
+                             where t.owner not like '%SYS%' and t.num_rows is not null`
+	}
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var conID string
+		var pdb sql.NullString
+		var owner string
+		var name string
+		var space string
+		var value float64
+		if err := rows.Scan(&conID, &pdb, &owner, &name, &space, &value); err != nil {
+			return err
 		}
+		name = cleanName(name)
+		e.tablerows.WithLabelValues(config.Database, config.Instance, conID, pdb.String, owner, name, space).Set(value)
 	}
+	return rows.Err()
 }
 
-func (e *Exporter) ScrapeTablebytes() {
-     // ScrapeTablebytes collects bytes from dba_tables/dba_segments view.
-     var (
-          rows *sql.Rows
-          err  error
-     )
-	 for _, config := range e.configs {
-		db := config.db
-	
-        if db != nil {
-             rows, err = db.Query(`SELECT tab.owner, tab.table_name,  stab.bytes
+func (e *Exporter) ScrapeTablebytes(ctx context.Context, config *Config) error {
+	// ScrapeTablebytes collects bytes from dba_tables/dba_segments view.
+	db := config.db
+
+	query := `SELECT 0, NULL, tab.owner, tab.table_name,  stab.bytes
                                FROM dba_tables  tab, dba_segments stab
                                WHERE stab.owner = tab.owner AND stab.segment_name = tab.table_name
-                               AND tab.owner NOT LIKE '%SYS%'`)
-             if err != nil {
-                  fmt.Println(err)
-                  return
-             }
-             defer rows.Close()
-             for rows.Next() {
-                  var owner string
-                  var name string
-                  var value float64
-                  if err = rows.Scan(&owner, &name, &value); err != nil {
-                       break
-                  }
-                  name = cleanName(name)
-                  e.tablebytes.WithLabelValues(config.Database, config.Instance, owner, name).Set(value)
-             }
+                               AND tab.owner NOT LIKE '%SYS%'`
+	if config.cdb {
+		query = `SELECT tab.con_id, c.name, tab.owner, tab.table_name, stab.bytes
+                               FROM cdb_tables tab JOIN cdb_segments stab
+                               ON stab.con_id = tab.con_id AND stab.owner = tab.owner AND stab.segment_name = tab.table_name
+                               LEFT JOIN v$containers c ON c.con_id = tab.con_id
+                               WHERE tab.owner NOT LIKE '%SYS%'`
+	}
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var conID string
+		var pdb sql.NullString
+		var owner string
+		var name string
+		var value float64
+		if err = rows.Scan(&conID, &pdb, &owner, &name, &value); err != nil {
+			return err
 		}
-	 }	
+		name = cleanName(name)
+		e.tablebytes.WithLabelValues(config.Database, config.Instance, conID, pdb.String, owner, name).Set(value)
+	}
+	return rows.Err()
 }
 
 // ScrapeTablebytes collects bytes from dba_indexes/dba_segments view.
-func (e *Exporter) ScrapeIndexbytes() {
-     var (
-          rows *sql.Rows
-          err  error
-     )
-	 for _, config := range e.configs {
-		db := config.db
-		if db != nil {
-           rows, err = db.Query(`select table_owner,table_name, sum(bytes)
+func (e *Exporter) ScrapeIndexbytes(ctx context.Context, config *Config) error {
+	db := config.db
+	query := `select 0, NULL, table_owner,table_name, sum(bytes)
                              from dba_indexes ind, dba_segments seg
                              WHERE ind.owner=seg.owner and ind.index_name=seg.segment_name
                              and table_owner NOT LIKE '%SYS%'
-                             group by table_owner,table_name`)
-           if err != nil {
-                fmt.Println(err)
-                return
-           }
-           defer rows.Close()
-           for rows.Next() {
-                var owner string
-                var name string
-                var value float64
-                if err = rows.Scan(&owner, &name, &value); err != nil {
-                     break
-                }
-                name = cleanName(name)
-                e.indexbytes.WithLabelValues(config.Database, config.Instance, owner, name).Set(value)
-           }
+                             group by table_owner,table_name`
+	if config.cdb {
+		query = `select ind.con_id, c.name, ind.table_owner, ind.table_name, sum(seg.bytes)
+                             from cdb_indexes ind JOIN cdb_segments seg
+                             ON ind.con_id=seg.con_id and ind.owner=seg.owner and ind.index_name=seg.segment_name
+                             LEFT JOIN v$containers c ON c.con_id = ind.con_id
+                             WHERE ind.table_owner NOT LIKE '%SYS%'
+                             group by ind.con_id, c.name, ind.table_owner, ind.table_name`
+	}
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var conID string
+		var pdb sql.NullString
+		var owner string
+		var name string
+		var value float64
+		if err = rows.Scan(&conID, &pdb, &owner, &name, &value); err != nil {
+			return err
 		}
-	 }
+		name = cleanName(name)
+		e.indexbytes.WithLabelValues(config.Database, config.Instance, conID, pdb.String, owner, name).Set(value)
+	}
+	return rows.Err()
 }
 
 // ScrapeLobbytes collects bytes from dba_lobs/dba_segments view.
-func (e *Exporter) ScrapeLobbytes() {
-     var (
-          rows *sql.Rows
-          err  error
-     )
-	 for _, config := range e.configs {
-		db := config.db
-          if db != nil {
-               rows, err = db.Query(`select l.owner, l.table_name, sum(bytes)
+func (e *Exporter) ScrapeLobbytes(ctx context.Context, config *Config) error {
+	db := config.db
+	query := `select 0, NULL, l.owner, l.table_name, sum(bytes)
                                  from dba_lobs l, dba_segments seg
                                  WHERE l.owner=seg.owner and l.table_name=seg.segment_name
                                  and l.owner NOT LIKE '%SYS%'
-                                 group by l.owner,l.table_name`)
-               if err != nil {
-                    fmt.Println(err)
-                    return
-               }
-               defer rows.Close()
-               for rows.Next() {
-                    var owner string
-                    var name string
-                    var value float64
-                    if err = rows.Scan(&owner, &name, &value); err != nil {
-                         break
-                    }
-                    name = cleanName(name)
-                    e.lobbytes.WithLabelValues(config.Database, config.Instance, owner, name).Set(value)
-               }
-		  }  
-   }
+                                 group by l.owner,l.table_name`
+	if config.cdb {
+		query = `select l.con_id, c.name, l.owner, l.table_name, sum(seg.bytes)
+                                 from cdb_lobs l JOIN cdb_segments seg
+                                 ON l.con_id=seg.con_id and l.owner=seg.owner and l.table_name=seg.segment_name
+                                 LEFT JOIN v$containers c ON c.con_id = l.con_id
+                                 WHERE l.owner NOT LIKE '%SYS%'
+                                 group by l.con_id, c.name, l.owner, l.table_name`
+	}
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var conID string
+		var pdb sql.NullString
+		var owner string
+		var name string
+		var value float64
+		if err = rows.Scan(&conID, &pdb, &owner, &name, &value); err != nil {
+			return err
+		}
+		name = cleanName(name)
+		e.lobbytes.WithLabelValues(config.Database, config.Instance, conID, pdb.String, owner, name).Set(value)
+	}
+	return rows.Err()
 }
 
 // Describe describes all the metrics exported by the Oracle exporter.
 func (e *Exporter) Describe(ch chan<- *prometheus.Desc) {
-     e.duration.Describe(ch)
-     e.totalScrapes.Describe(ch)
-     e.scrapeErrors.Describe(ch)
-     e.session.Describe(ch)
-     e.sysstat.Describe(ch)
-     e.waitclass.Describe(ch)
-     e.sysmetric.Describe(ch)
-     e.interconnect.Describe(ch)
-     e.tablespace.Describe(ch)
-     e.recovery.Describe(ch)
-     e.redo.Describe(ch)
-     e.cache.Describe(ch)
-     e.uptime.Describe(ch)
-     e.up.Describe(ch)
-     e.alertlog.Describe(ch)
-     e.alertdate.Describe(ch)
-     e.services.Describe(ch)
-     e.parameter.Describe(ch)
-     //e.query.Describe(ch)
-     e.asmspace.Describe(ch)
-     e.tablerows.Describe(ch)
-     e.tablebytes.Describe(ch)
-     e.indexbytes.Describe(ch)
-     e.lobbytes.Describe(ch)
-     for _, metric := range e.custom {
-          metric.Describe(ch)
-     }
+	e.duration.Describe(ch)
+	e.totalScrapes.Describe(ch)
+	e.scrapeErrors.Describe(ch)
+	e.timeouts.Describe(ch)
+	e.customErrors.Describe(ch)
+	e.collectorTime.Describe(ch)
+	e.collectorUp.Describe(ch)
+	e.lastSuccess.Describe(ch)
+	e.queryAge.Describe(ch)
+	e.connectError.Describe(ch)
+	configReloadSuccess.Describe(ch)
+	configReloadTime.Describe(ch)
+	e.error.Describe(ch)
+	e.up.Describe(ch)
+	for _, c := range collectors {
+		for _, metric := range c.metrics(e) {
+			metric.Describe(ch)
+		}
+	}
+	for _, desc := range e.custom {
+		ch <- desc
+	}
 }
 
-// Connect the DBs and gather Databasename and Instancename
-func (e *Exporter) Connect() {
-     e.up.Reset()
-     e.session.Reset()
-     e.sysstat.Reset()
-     e.waitclass.Reset()
-     e.sysmetric.Reset()
-     e.interconnect.Reset()
-     e.tablespace.Reset()
-     e.recovery.Reset()
-     e.redo.Reset()
-     e.cache.Reset()
-     e.uptime.Reset()
-     e.alertlog.Reset()
-     e.alertdate.Reset()
-     e.services.Reset()
-     e.parameter.Reset()
-
-     e.asmspace.Reset()
-     e.tablerows.Reset()
-     e.tablebytes.Reset()
-     e.indexbytes.Reset()
-     e.lobbytes.Reset()
-
-     for _, metric := range e.custom {
-          metric.Reset()
-     }
-    for i, config := range e.configs {
-
-     log.Infoln(fmt.Sprintf("open dbConnection %d ", i) + "for "+ config.Database+"/"+config.Instance)
-     db , err := sql.Open("oci8", config.Connection)
-   
-	 if err != nil {
-		log.Error("db for config" + config.Database + "/"+ config.Instance + ":")
-		fmt.Println(err)
-		e.up.WithLabelValues(config.Database,config.Instance).Set(0)
-		return
-	 }
-	 // test connection
-	 if db != nil {
-		rows, err := db.Query(`select 1 from dual`)
-		if err != nil {
-   		  log.Error("Db error for config " + config.Database + "/"+ config.Instance + ":")
-		  fmt.Println(err)
-
-		  e.up.WithLabelValues(config.Database,config.Instance).Set(0)
-  
-		  if db != nil {
-			db.Close()
-			config.db = nil
-			 log.Infoln("closed dbConnection on error for "+ config.Database+"/"+config.Instance)
-	  
-		   }
-		  continue
+// Connect assigns the pooled connection of each DB and checks that it is up
+func (e *Exporter) Connect(ctx context.Context) {
+	e.up.Reset()
+	e.error.Reset()
+	e.connectError.Reset()
+	e.collectorTime.Reset()
+	e.collectorUp.Reset()
+	// lastSuccess is kept, it must survive the scrapes between two background
+	// runs and the ones in which a collector failed
+
+	// check all DBs in parallel, a hanging one must not delay the others
+	results := make(chan connection, len(e.configs))
+	for _, config := range e.configs {
+		config.db = nil
+		e.error.WithLabelValues(config.Database, config.Instance).Set(0)
+		go func(config *Config) {
+			results <- connect(ctx, config)
+		}(config)
+	}
+	// DBs which don't answer until the deadline are down, their late
+	// results are dropped into the buffered channel
+	done := map[*Config]bool{}
+	for len(done) < len(e.configs) {
+		select {
+		case c := <-results:
+			done[c.config] = true
+			e.connected(c)
+		case <-ctx.Done():
+			for _, config := range e.configs {
+				if !done[config] {
+					dbLogger(config).Errorln("database is not reachable: ", "connecting did not finish before the scrape deadline")
+					e.connected(connection{config: config, err: ctx.Err()})
+				}
+			}
+			return
 		}
-  
-		rows.Close()
-  
-	 }
+	}
+}
+
+// connection is the outcome of connecting to a DB.
+type connection struct {
+	config  *Config
+	db      *sql.DB
+	open    bool
+	mounted bool
+	cdb     bool
+	err     error
+}
 
-     // only assigned working db connection
-     config.db = db
-	 // db is up:
-	 e.up.WithLabelValues(config.Database,config.Instance).Set(1)
+// connect checks the pooled connection of the DB. It doesn't touch the config
+// or the metrics, it may still run after the scrape gave up on it.
+func connect(ctx context.Context, config *Config) connection {
+	db, err := openPool(config)
+	if err != nil {
+		dbLogger(config).Errorln("cannot open connection pool: ", config.redact(err))
+		return connection{config: config, err: err}
+	}
+	// test connection, reopen the pool once if it went stale
+	if err = checkPool(ctx, db); err != nil && ctx.Err() == nil {
+		dropPool(config)
+		db, err = openPool(config)
+		if err == nil {
+			err = checkPool(ctx, db)
+		}
+	}
+	if err != nil {
+		dbLogger(config).Errorln("database is not reachable: ", config.redact(err))
+		if ctx.Err() == nil {
+			dropPool(config)
+		}
+		return connection{config: config, err: err}
+	}
 
-  }
+	open, mounted, cdb, err := inspect(ctx, db)
+	if err != nil {
+		dbLogger(config).Errorln("cannot read the instance status: ", config.redact(err))
+	}
+	return connection{config: config, db: db, open: open, mounted: mounted, cdb: cdb}
 }
 
-// Close Connections
-func (e *Exporter) Close() {
-  for _, config := range e.configs {
+// connected assigns the working connection of a DB and sets its status metrics.
+func (e *Exporter) connected(c connection) {
+	config := c.config
+	if c.err != nil {
+		e.up.WithLabelValues(config.Database, config.Instance).Set(0)
+		e.connectError.WithLabelValues(config.Database, config.Instance, connectErrorReason(c.err)).Set(1)
+		e.scrapeFailed(config)
+		return
+	}
+	// only assigned working db connection
+	config.db, config.open, config.mounted, config.cdb = c.db, c.open, c.mounted, c.cdb
+	// db is up:
+	e.up.WithLabelValues(config.Database, config.Instance).Set(1)
+}
 
-	if config.db != nil {
-       config.db.Close()
-       log.Infoln("closed dbConnection on for "+ config.Database+"/"+config.Instance)
-       config.db = nil
+// jobs returns the enabled collectors to run against every connected DB, the
+// custom queries add their samples to custom.
+func (e *Exporter) jobs(samples *scrapeSamples) []scrapeJob {
+	var jobs []scrapeJob
+	for _, config := range e.configs {
+		for _, c := range collectors {
+			// local collectors don't need the DB, background ones are served from cache
+			if !e.enabled(c, config) || c.background() || (config.db == nil && !c.local) {
+				continue
+			}
+			if c.needsOpen && !config.open {
+				dbLogger(config).With("collector", c.name).Debugln("skipped, the instance is not open")
+				continue
+			}
+			if c.needsMount && !config.mounted {
+				dbLogger(config).With("collector", c.name).Debugln("skipped, the instance has no mounted database")
+				continue
+			}
+			c := c
+			jobs = append(jobs, scrapeJob{name: c.name, config: config, local: c.local, timeout: *queryTimeout,
+				scrape: func(ctx context.Context, config *Config) (func(), error) {
+					sink := e.newSink()
+					err := c.scrape(sink, ctx, config)
+					// the samples collected before an error are kept
+					metrics := snapshot(c.metrics(sink))
+					return func() { samples.metrics = append(samples.metrics, metrics...) }, err
+				}})
+		}
+		if config.db == nil {
+			continue
+		}
+		for _, query := range config.Queries {
+			// run in the background, see collectCachedQueries
+			if query.Interval > 0 {
+				continue
+			}
+			query := query
+			job := scrapeJob{name: "custom_" + query.Name, config: config, timeout: *queryTimeout}
+			job.scrape = func(ctx context.Context, config *Config) (func(), error) {
+				custom, err := e.ScrapeCustomQuery(ctx, config, query, *pNoRownum)
+				return func() { samples.custom.add(custom) }, err
+			}
+			if query.Timeout > 0 {
+				job.timeout = query.Timeout
+			}
+			jobs = append(jobs, job)
+		}
 	}
-  }
+	return jobs
 }
 
 // Collect implements prometheus.Collector.
 func (e *Exporter) Collect(ch chan<- prometheus.Metric) {
-  begun := time.Now()
-
-  e.Connect()
-  for _, config := range e.configs {
-	e.totalScrapes.WithLabelValues(config.Database,config.Instance).Inc()
-  }	
-  defer e.Close()
-
-	 e.up.Collect(ch)
-
-     if e.vRecovery || *pRecovery {
-          e.ScrapeRecovery()
-          e.recovery.Collect(ch)
-     }
-
-     if *pMetrics {
-          e.ScrapeUptime()
-          e.uptime.Collect(ch)
-
-          e.ScrapeSession()
-          e.session.Collect(ch)
-
-          e.ScrapeSysstat()
-          e.sysstat.Collect(ch)
-
-          e.ScrapeWaitclass()
-          e.waitclass.Collect(ch)
-
-          e.ScrapeSysmetric()
-          e.sysmetric.Collect(ch)
-
-          e.ScrapeTablespace()
-          e.tablespace.Collect(ch)
+	begun := time.Now()
 
-          e.ScrapeInterconnect()
-          e.interconnect.Collect(ch)
+	ctx, cancel := e.scrapeContext()
+	defer cancel()
 
-          e.ScrapeRedo()
-          e.redo.Collect(ch)
-
-          e.ScrapeCache()
-          e.cache.Collect(ch)
-
-          e.ScrapeAlertlog()
-          e.alertlog.Collect(ch)
-          e.alertdate.Collect(ch)
-
-          e.ScrapeServices()
-          e.services.Collect(ch)
-
-          e.ScrapeParameter()
-          e.parameter.Collect(ch)
-
-          e.ScrapeAsmspace()
-          e.asmspace.Collect(ch)
-     }
-
-     e.ScrapeCustomQueries(*pNoRownum)
-     for _, metric := range e.custom {
-          metric.Collect(ch)
-     }
-     //e.ScrapeQuery()
-     //e.query.Collect(ch)
-
-     if e.vTabRows || *pTabRows {
-          e.ScrapeTablerows()
-          e.tablerows.Collect(ch)
-     }
-
-     if e.vTabBytes || *pTabBytes {
-          e.ScrapeTablebytes()
-          e.tablebytes.Collect(ch)
-     }
-
-     if e.vIndBytes || *pIndBytes {
-          e.ScrapeIndexbytes()
-          e.indexbytes.Collect(ch)
-     }
-
-     if e.vLobBytes || *pLobBytes {
-          e.ScrapeLobbytes()
-          e.lobbytes.Collect(ch)
-     }
+	e.Connect(ctx)
+	for _, config := range e.configs {
+		e.totalScrapes.WithLabelValues(config.Database, config.Instance).Inc()
+	}
 
-     e.duration.WithLabelValues().Set(time.Since(begun).Seconds())
-	 e.duration.Collect(ch)
-	 e.totalScrapes.Collect(ch)
-	 e.error.Collect(ch)
-	 e.scrapeErrors.Collect(ch)
+	// collectors which did not finish in time simply have no samples
+	samples := newScrapeSamples()
+	e.scrape(ctx, e.jobs(samples))
+	e.collectBackground(ch)
+
+	e.up.Collect(ch)
+	e.collectCachedQueries(samples.custom)
+	samples.collect(ch)
+	e.queryAge.Collect(ch)
+
+	e.duration.WithLabelValues().Set(time.Since(begun).Seconds())
+	e.duration.Collect(ch)
+	e.totalScrapes.Collect(ch)
+	e.error.Collect(ch)
+	e.connectError.Collect(ch)
+	e.scrapeErrors.Collect(ch)
+	e.timeouts.Collect(ch)
+	e.customErrors.Collect(ch)
+	e.collectorTime.Collect(ch)
+	e.collectorUp.Collect(ch)
+	e.lastSuccess.Collect(ch)
+	configReloadSuccess.Collect(ch)
+	configReloadTime.Collect(ch)
 
 }
 
 func (e *Exporter) Handler(w http.ResponseWriter, r *http.Request) {
-  promhttp.Handler().ServeHTTP(w, r)
+	promhttp.Handler().ServeHTTP(w, r)
 }
 
-
 //func createKeyValuePairs(m map[string]string) string {
 //    b := new(bytes.Buffer)
 //    for key, value := range m {
@@ -1177,92 +1225,115 @@ func (e *Exporter) Handler(w http.ResponseWriter, r *http.Request) {
 //}
 
 func ScrapeHandler(w http.ResponseWriter, r *http.Request) {
-  
-
-  target := r.URL.Query().Get("target")
-  target_plusopts := r.URL.String()
-
-  log.Infoln("ScrapeHandler for " + target_plusopts)
-
-  if handlers[target_plusopts] != nil {
-  	log.Infoln("resuse Exporter" + target_plusopts)
-  } else {
-    registry := prometheus.NewRegistry()
-    e := NewExporter()
-	e.vTabRows = false
-	e.vTabBytes = false
-	e.vIndBytes = false
-	e.vLobBytes = false
-	e.vRecovery = false
-	if r.URL.Query().Get("tablerows") == "true" {
-		 e.vTabRows = true
-	}
-	if r.URL.Query().Get("tablebytes") == "true" {
-		 e.vTabBytes = true
+
+	target := r.URL.Query().Get("target")
+	target_plusopts := r.URL.String()
+
+	log.Infoln("ScrapeHandler for " + target_plusopts)
+
+	handlersMu.Lock()
+	h := handlers[target_plusopts]
+	if h != nil {
+		log.Infoln("resuse Exporter" + target_plusopts)
+	} else {
+		var err error
+		h, err = newScrapeHandler(r)
+		if err != nil {
+			handlersMu.Unlock()
+			http.Error(w, err.Error(), 400)
+			return
+		}
+		handlers[target_plusopts] = h
 	}
-	if r.URL.Query().Get("indexbytes") == "true" {
-		 e.vIndBytes = true
+	handlersMu.Unlock()
+
+	// Delegate http serving to Prometheus client library, which will call collector.Collect.
+	if h == nil {
+		http.Error(w, fmt.Sprintf("Target not found %v", target), 400)
+		return
 	}
-	if r.URL.Query().Get("lobbytes") == "true" {
-		 e.vLobBytes = true
+
+	h.ServeHTTP(w, r)
+}
+
+// newScrapeHandler builds the exporter for the target and collectors of the request.
+func newScrapeHandler(r *http.Request) (http.Handler, error) {
+	target := r.URL.Query().Get("target")
+	collect, err := parseCollect(r.URL.Query())
+	if err != nil {
+		return nil, err
 	}
-	if r.URL.Query().Get("recovery") == "true" {
-		 e.vRecovery = true
+	registry := prometheus.NewRegistry()
+	e := NewExporter()
+	e.collect = collect
+
+	c := []*Config{}
+
+	conf := currentConfig()
+	for i, conn := range conf.Cfgs {
+		log.Infoln("check Database" + conn.Database + " vs " + target)
+		if target == "" || conn.Database == target {
+			log.Infoln("add Database" + conn.Database)
+			// Note: reference orig list element, as conn reference is updated
+			cp := conf.Cfgs[i]
+			c = append(c, &cp)
+		}
 	}
-  
-	c:=[]*Config{}
-	
-    for i, conn := range config.Cfgs {
-       log.Infoln("check Database" + conn.Database+ " vs " + target)
-       if target == "" || conn.Database == target {
-		  log.Infoln("add Database" + conn.Database)
-		  // Note: reference orig list element, as conn reference is updated
-		  cp :=config.Cfgs[i]
-		  c = append(c,&cp)
-        }
-     }
 	e.configs = c
 
 	e.lastIp = ""
 	ip, _, err := net.SplitHostPort(r.RemoteAddr)
 	if err == nil {
-		 e.lastIp = ip
+		e.lastIp = ip
 	}
-	registry.MustRegister(e)
-	handlers[target_plusopts] = promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
-
-  }
+	// custom metrics which clash with others fail the request, not the exporter
+	if err := registry.Register(e); err != nil {
+		log.Errorln("error registering the metrics: ", err)
+		return nil, err
+	}
+	h := promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// one scrape per exporter at a time, the deadline is taken from the current request
+		e.mu.Lock()
+		defer e.mu.Unlock()
+		e.timeout = scrapeTimeoutFor(r)
+		h.ServeHTTP(w, r)
+	}), nil
+}
 
-  // Delegate http serving to Prometheus client library, which will call collector.Collect.
-  h := handlers[target_plusopts]
+// resetHandlers drops the cached exporters, they are rebuilt on the next scrape.
+func resetHandlers() {
+	handlersMu.Lock()
+	defer handlersMu.Unlock()
+	handlers = map[string]http.Handler{}
+}
 
-  if h == nil {
-    http.Error(w, fmt.Sprintf("Target not found %v", target), 400)
-    return
-  } 
+func main() {
+	flag.Parse()
+	initCollectors()
 
-  h.ServeHTTP(w, r)
-}
+	if *configCheck {
+		os.Exit(checkConfig())
+	}
 
+	manageService()
 
-func main() {
-     flag.Parse()
+	log.Infoln("Starting Prometheus Oracle exporter " + Version)
+	//metricsExporter = NewExporter()
+	if loadConfig() {
+		log.Infoln("Config loaded: ", *configFile)
+		//exporter := NewExporter()
+		//prometheus.MustRegister(exporter)
 
-     manageService()
-     
-     log.Infoln("Starting Prometheus Oracle exporter " + Version)
-    //metricsExporter = NewExporter()
-     if loadConfig() {
-          log.Infoln("Config loaded: ", *configFile)
-          //exporter := NewExporter()
-          //prometheus.MustRegister(exporter)
+		watchReloadSignal()
 
-          http.HandleFunc(*metricPath, ScrapeHandler)
-          //http.HandleFunc("/telemetrie", exporter.Handler)
+		http.HandleFunc(*metricPath, ScrapeHandler)
+		http.HandleFunc("/-/reload", ReloadHandler)
+		//http.HandleFunc("/telemetrie", exporter.Handler)
 
-          http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) { w.Write(landingPage) })
+		http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) { w.Write(landingPage) })
 
-          log.Infoln("Listening on", *listenAddress)
-          log.Fatal(http.ListenAndServe(*listenAddress, nil))
-     }
+		log.Infoln("Listening on", *listenAddress)
+		log.Fatal(http.ListenAndServe(*listenAddress, nil))
+	}
 }
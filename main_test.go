@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"database/sql/driver"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestConnectDeadline(t *testing.T) {
+	hang := make(chan struct{})
+	defer close(hang)
+	up := addFakeDB("connect_up", &fakeDB{results: upResults()})
+	down := addFakeDB("connect_hang", &fakeDB{hang: hang})
+	defer dropPool(up)
+	defer dropPool(down)
+
+	e := NewExporter()
+	e.configs = []*Config{up, down}
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	begun := time.Now()
+	e.Connect(ctx)
+	if d := time.Since(begun); d > time.Second {
+		t.Fatalf("Connect took %v, the deadline was 200ms", d)
+	}
+	if up.db == nil || !up.open {
+		t.Errorf("connect_up: db %v, open %v, want connected and open", up.db, up.open)
+	}
+	if down.db != nil {
+		t.Errorf("connect_hang: has a connection")
+	}
+	tests := []struct {
+		config *Config
+		want   float64
+	}{
+		{up, 1},
+		{down, 0},
+	}
+	for _, tt := range tests {
+		if got := testutil.ToFloat64(e.up.WithLabelValues(tt.config.Database, tt.config.Instance)); got != tt.want {
+			t.Errorf("%s: oracledb_up = %v, want %v", tt.config.Database, got, tt.want)
+		}
+	}
+	if got := testutil.ToFloat64(e.connectError.WithLabelValues(down.Database, down.Instance, "timeout")); got != 1 {
+		t.Errorf("connect_hang: connect_error{reason=timeout} = %v, want 1", got)
+	}
+}
+
+func TestSkipCollectorsWithoutDatabase(t *testing.T) {
+	// an ASM instance is started and has no v$database
+	asm := addFakeDB("connect_asm", &fakeDB{results: []fakeResult{
+		{match: "from dual", columns: []string{"1"}, rows: [][]driver.Value{{int64(1)}}},
+		{match: "v$instance", columns: []string{"status"}, rows: [][]driver.Value{{"STARTED"}}},
+	}})
+	defer dropPool(asm)
+
+	e := NewExporter()
+	e.configs = []*Config{asm}
+	e.collect = map[string]bool{"session": true, "redo": true, "archive": true, "tablespace": true}
+	e.Connect(context.Background())
+	if asm.db == nil || asm.open || asm.mounted {
+		t.Fatalf("db %v, open %v, mounted %v, want connected, neither open nor mounted", asm.db, asm.open, asm.mounted)
+	}
+
+	var names []string
+	for _, job := range e.jobs(newScrapeSamples()) {
+		names = append(names, job.name)
+	}
+	if len(names) != 1 || names[0] != "session" {
+		t.Errorf("jobs %v, want [session]", names)
+	}
+}
+
+func TestConnectKeepsLastSuccess(t *testing.T) {
+	e := NewExporter()
+	e.lastSuccess.WithLabelValues("tablerows", "db", "db").Set(1000)
+	e.collectorUp.WithLabelValues("tablerows", "db", "db").Set(1)
+
+	e.Connect(context.Background())
+
+	if got := testutil.ToFloat64(e.lastSuccess.WithLabelValues("tablerows", "db", "db")); got != 1000 {
+		t.Errorf("collector_last_success_timestamp_seconds = %v after Connect, want 1000", got)
+	}
+	if got := testutil.CollectAndCount(e.collectorUp); got != 0 {
+		t.Errorf("collector_success has %d samples after Connect, want 0", got)
+	}
+}
+
+func TestScrapeHandlerRejectsClashingMetrics(t *testing.T) {
+	defer setConfig(currentConfig())
+	// passes no validation, e.g. a config set before the check existed
+	setConfig(Configs{Cfgs: []Config{{Database: "clash", Instance: "clash", Queries: []Query{
+		{Name: "foo", Help: "foo", Metrics: []QueryMetric{{Column: "v", Name: "oracledb_up"}}},
+	}}}})
+
+	r := httptest.NewRequest("GET", "/metrics", nil)
+	if _, err := newScrapeHandler(r); err == nil {
+		t.Errorf("handler for a custom metric oracledb_up was built, want an error")
+	}
+}
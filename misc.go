@@ -2,15 +2,16 @@ package main
 
 import (
 	"database/sql"
+	"fmt"
 	"io/ioutil"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
-	_ "github.com/mattn/go-oci8"
 	"github.com/prometheus/common/log"
-	"gopkg.in/yaml.v2"
+	"gopkg.in/yaml.v3"
 )
 
 type Alert struct {
@@ -19,20 +20,85 @@ type Alert struct {
 }
 
 type Query struct {
-	Sql     string   `yaml:"sql"`
-	Name    string   `yaml:"name"`
-	Metrics []string `yaml:"metrics"`
-	Labels  []string `yaml:"labels"`
-	Help    string   `yaml:"help"`
+	Sql     string        `yaml:"sql" toml:"sql"`
+	Name    string        `yaml:"name" toml:"name"`
+	Metrics []QueryMetric `yaml:"metrics" toml:"metrics"`
+	Labels  []string      `yaml:"labels" toml:"labels"`
+	Help    string        `yaml:"help" toml:"help"`
+	Timeout time.Duration `yaml:"timeout" toml:"timeout"`
+	// run at most once per interval and serve the cached result in between,
+	// a result older than ttl is dropped (0 keeps it until the next run)
+	Interval time.Duration `yaml:"interval" toml:"interval"`
+	TTL      time.Duration `yaml:"ttl" toml:"ttl"`
+	// file and line of the definition and of its keys, for error messages
+	source string
+	keys   keySources
+}
+
+// QueryMetric is a metric of a custom query. A plain column name becomes a
+// sample of oracledb_custom_<query>, with a name the column is exported as a
+// metric of its own type.
+type QueryMetric struct {
+	Column string `yaml:"column" toml:"column"`
+	Name   string `yaml:"name" toml:"name"`
+	Help   string `yaml:"help" toml:"help"`
+	// gauge (default), counter, untyped or histogram
+	Type string `yaml:"type" toml:"type"`
+	// histogram only: bucket column -> upper bound, columns with count and sum
+	Buckets map[string]float64 `yaml:"buckets" toml:"buckets"`
+	Count   string             `yaml:"count" toml:"count"`
+	Sum     string             `yaml:"sum" toml:"sum"`
+}
+
+// UnmarshalYAML accepts a plain column name as well as a metric definition.
+func (m *QueryMetric) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		return value.Decode(&m.Column)
+	}
+	// the strict mode of the config decoder does not reach into Node.Decode
+	if err := checkKeys(value, "column", "name", "help", "type", "buckets", "count", "sum"); err != nil {
+		return err
+	}
+	type plain QueryMetric
+	return value.Decode((*plain)(m))
 }
 
 type Config struct {
-	Connection string  `yaml:"connection"`
-	Database   string  `yaml:"database"`
-	Instance   string  `yaml:"instance"`
-	Alertlog   []Alert `yaml:"alertlog"`
-	Queries    []Query `yaml:"queries"`
-	db         *sql.DB
+	// <user>/<pass>@<tnsname>, or the separate user, password and dsn below
+	Connection   string `yaml:"connection"`
+	User         string `yaml:"user"`
+	Password     string `yaml:"password"`
+	PasswordFile string `yaml:"password_file"`
+	Dsn          string `yaml:"dsn"`
+	// administrative privilege of the connections: sysdba, sysasm or sysoper
+	// with oci8, sysdba or sysoper with goora
+	Role string `yaml:"role"`
+	// oci8 or goora, default from -db.driver
+	Driver string `yaml:"driver"`
+	// directory with sqlnet.ora, tnsnames.ora and the wallet of this connection
+	TnsAdmin string  `yaml:"tns_admin"`
+	Database string  `yaml:"database"`
+	Instance string  `yaml:"instance"`
+	Alertlog []Alert `yaml:"alertlog"`
+	Queries  []Query `yaml:"queries"`
+	// named sets of queries from the -queries.dir, added to the queries
+	QuerySets []string `yaml:"query_sets"`
+	// switch built-in collectors on or off for this connection
+	Collectors map[string]bool `yaml:"collectors"`
+	// connection pool settings, defaults from the -db.* flags
+	MaxOpenConns    int           `yaml:"max_open_conns"`
+	MaxIdleConns    int           `yaml:"max_idle_conns"`
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime"`
+	db              *sql.DB
+	// whether the instance is open, otherwise it is only started or mounted,
+	// whether it has a mounted database, and whether it is a container
+	// database, see inspect
+	open    bool
+	mounted bool
+	cdb     bool
+	// file and line of the definition and of its keys, for error messages
+	source string
+	keys   keySources
 }
 
 type Configs struct {
@@ -40,8 +106,10 @@ type Configs struct {
 }
 
 var (
-	config Configs
-	pwd    string
+	// the current configuration, replaced on reload, see currentConfig
+	config   Configs
+	configMu sync.RWMutex
+	pwd      string
 )
 
 // Oracle gives us some ugly names back. This function cleans things up for Prometheus.
@@ -54,6 +122,14 @@ func cleanName(s string) string {
 	return s
 }
 
+// boolValue returns 1 for true and 0 for false.
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
 func cleanIp(s string) string {
 	s = strings.Replace(s, ":", "", -1)  // Remove spaces
 	s = strings.Replace(s, ".", "_", -1) // Remove open parenthesis
@@ -66,18 +142,64 @@ func loadConfig() bool {
 		log.Fatalf("error: %v", err)
 	}
 	pwd = path
-	content, err := ioutil.ReadFile(*configFile)
+	conf, err := readConfig(*configFile)
 	if err != nil {
 		log.Fatalf("error: %v", err)
 		return false
+	}
+	setConfig(conf)
+	return true
+}
+
+// readConfig parses the config file, resolves its query sets and validates
+// the result. All problems found are returned as configErrors.
+func readConfig(file string) (Configs, error) {
+	var conf Configs
+	content, err := ioutil.ReadFile(file)
+	if err != nil {
+		return conf, err
+	}
+	doc, err := decodeYAML(content, &conf)
+	if err != nil {
+		return conf, fmt.Errorf("%s: %v", file, err)
+	}
+	if err := expandEnv(&conf); err != nil {
+		return conf, fmt.Errorf("%s: %v", file, err)
+	}
+	for i := range conf.Cfgs {
+		conf.Cfgs[i].source = source(file, nodeLine(doc, "connections", i))
+		conf.Cfgs[i].keys = nodeKeySources(file, nodeAt(doc, "connections", i))
+		for j := range conf.Cfgs[i].Queries {
+			conf.Cfgs[i].Queries[j].source = source(file, nodeLine(doc, "connections", i, "queries", j))
+			conf.Cfgs[i].Queries[j].keys = nodeKeySources(file, nodeAt(doc, "connections", i, "queries", j))
+		}
+	}
+
+	sets, err := loadQuerySets(*queriesDir)
+	if err != nil {
+		return conf, err
+	}
+	problems := resolveQuerySets(&conf, sets)
+	errs, warnings := validateConfig(&conf)
+	problems = append(problems, errs...)
+	// paths may only exist on the host the exporter runs on, they only fail
+	// the check, at runtime the collector or the connection reports them
+	if *configCheck {
+		problems = append(problems, warnings...)
 	} else {
-		err := yaml.Unmarshal(content, &config)
-		if err != nil {
-			log.Fatalf("error: %v", err)
-			return false
+		for _, warning := range warnings {
+			log.Warnln(warning)
 		}
-		return true
 	}
+	for i := range conf.Cfgs {
+		if err := conf.Cfgs[i].loadPassword(); err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", conf.Cfgs[i].source, err))
+		}
+	}
+	if len(problems) > 0 {
+		return conf, problems
+	}
+	return conf, nil
 }
 
 func ReadAccess() {
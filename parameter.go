@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"strconv"
+)
+
+var parameterNames = listFlag{"sessions", "processes", "sga_target", "pga_aggregate_target", "db_recovery_file_dest_size"}
+
+func init() {
+	flag.Var(&parameterNames, "collector.parameter.names", "Comma separated names of the parameters of v$parameter to export.")
+}
+
+// ScrapeParameter collects the parameters of -collector.parameter.names from
+// the v$parameter view, numbers as value and all others in a label.
+func (e *Exporter) ScrapeParameter(ctx context.Context, config *Config) error {
+	if len(parameterNames) == 0 {
+		return nil
+	}
+	db := config.db
+
+	// type 3 is integer and 6 big integer, the value is always a string
+	binds, args := bindList(1, parameterNames)
+	rows, err := db.QueryContext(ctx, `select name, type, value, isdefault, ismodified
+                                 from v$parameter where name in (`+binds+`)`, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name string
+		var valueType int
+		var value sql.NullString
+		var isdefault string
+		var ismodified string
+		if err := rows.Scan(&name, &valueType, &value, &isdefault, &ismodified); err != nil {
+			return err
+		}
+		name = cleanName(name)
+		if valueType == 3 || valueType == 6 {
+			if number, err := strconv.ParseFloat(value.String, 64); err == nil {
+				e.parameter.WithLabelValues(config.Database, config.Instance, name, isdefault, ismodified).Set(number)
+				continue
+			}
+		}
+		e.parameterInfo.WithLabelValues(config.Database, config.Instance, name, value.String, isdefault, ismodified).Set(1)
+	}
+	return rows.Err()
+}
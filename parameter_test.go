@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"database/sql/driver"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestScrapeParameter(t *testing.T) {
+	// the columns name, type, value, isdefault and ismodified of v$parameter
+	rows := [][]driver.Value{
+		{"sessions", int64(3), "472", "TRUE", "FALSE"},
+		{"sga_target", int64(6), "4294967296", "FALSE", "MODIFIED"},
+		{"db_recovery_file_dest", int64(2), "+FRA", "FALSE", "FALSE"},
+		{"resource_limit", int64(1), "TRUE", "TRUE", "FALSE"},
+		// no value set
+		{"pga_aggregate_target", int64(6), nil, "TRUE", "FALSE"},
+		{"db_domain", int64(2), nil, "TRUE", "FALSE"},
+	}
+	config := addFakeDB("parameter", &fakeDB{results: upResults(fakeResult{match: "from v$parameter",
+		columns: []string{"NAME", "TYPE", "VALUE", "ISDEFAULT", "ISMODIFIED"}, rows: rows})})
+	defer dropPool(config)
+	var err error
+	if config.db, err = openPool(config); err != nil {
+		t.Fatal(err)
+	}
+
+	e := NewExporter()
+	if err := e.ScrapeParameter(context.Background(), config); err != nil {
+		t.Fatalf("scrape failed: %v", err)
+	}
+
+	// integers and big integers are values
+	numbers := []struct {
+		name      string
+		isdefault string
+		modified  string
+		want      float64
+	}{
+		{"sessions", "TRUE", "FALSE", 472},
+		{"sga_target", "FALSE", "MODIFIED", 4294967296},
+	}
+	if got := testutil.CollectAndCount(e.parameter); got != len(numbers) {
+		t.Errorf("oracledb_parameter has %d samples, want %d", got, len(numbers))
+	}
+	for _, tt := range numbers {
+		if got := testutil.ToFloat64(e.parameter.WithLabelValues("parameter", "parameter", tt.name, tt.isdefault, tt.modified)); got != tt.want {
+			t.Errorf("oracledb_parameter{name=%q} = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	// all others and the numbers without a value are in the label value
+	infos := []struct {
+		name      string
+		value     string
+		isdefault string
+	}{
+		{"db_recovery_file_dest", "+FRA", "FALSE"},
+		{"resource_limit", "TRUE", "TRUE"},
+		{"pga_aggregate_target", "", "TRUE"},
+		{"db_domain", "", "TRUE"},
+	}
+	if got := testutil.CollectAndCount(e.parameterInfo); got != len(infos) {
+		t.Errorf("oracledb_parameter_info has %d samples, want %d", got, len(infos))
+	}
+	for _, tt := range infos {
+		if got := testutil.ToFloat64(e.parameterInfo.WithLabelValues("parameter", "parameter", tt.name, tt.value, tt.isdefault, "FALSE")); got != 1 {
+			t.Errorf("oracledb_parameter_info{name=%q,value=%q} = %v, want 1", tt.name, tt.value, got)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+)
+
+// ScrapePdb collects the state of the pluggable databases from v$pdbs and
+// their resource usage from v$rsrcpdbmetric, only in a CDB.
+func (e *Exporter) ScrapePdb(ctx context.Context, config *Config) error {
+	if !config.cdb {
+		return nil
+	}
+	db := config.db
+
+	rows, err := db.QueryContext(ctx, `select name, open_mode, NVL(restricted,'NO'), NVL(total_size,0), recovery_status
+                                 from v$pdbs`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name string
+		var mode string
+		var restricted string
+		var size float64
+		var recovery sql.NullString
+		if err := rows.Scan(&name, &mode, &restricted, &size, &recovery); err != nil {
+			return err
+		}
+		e.pdbOpenMode.WithLabelValues(config.Database, config.Instance, name, mode).Set(1)
+		e.pdbRestricted.WithLabelValues(config.Database, config.Instance, name).Set(boolValue(restricted == "YES"))
+		e.pdbSize.WithLabelValues(config.Database, config.Instance, name).Set(size)
+		e.pdbRecovery.WithLabelValues(config.Database, config.Instance, name).Set(boolValue(recovery.String == "ENABLED"))
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	// the metrics of the last minute, closed PDBs have no rows
+	rows, err = db.QueryContext(ctx, `select p.name, m.cpu_consumed_time, m.cpu_wait_time, m.avg_cpu_utilization,
+                                 m.avg_running_sessions, m.iops, m.iombps
+                                 from v$rsrcpdbmetric m JOIN v$pdbs p ON p.con_id = m.con_id`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name string
+		var cpuTime, cpuWait, cpuUtil, sessions, iops, iombps float64
+		if err := rows.Scan(&name, &cpuTime, &cpuWait, &cpuUtil, &sessions, &iops, &iombps); err != nil {
+			return err
+		}
+		e.pdbResource.WithLabelValues(config.Database, config.Instance, name, "cpu_consumed_time").Set(cpuTime)
+		e.pdbResource.WithLabelValues(config.Database, config.Instance, name, "cpu_wait_time").Set(cpuWait)
+		e.pdbResource.WithLabelValues(config.Database, config.Instance, name, "avg_cpu_utilization").Set(cpuUtil)
+		e.pdbResource.WithLabelValues(config.Database, config.Instance, name, "avg_running_sessions").Set(sessions)
+		e.pdbResource.WithLabelValues(config.Database, config.Instance, name, "iops").Set(iops)
+		e.pdbResource.WithLabelValues(config.Database, config.Instance, name, "iombps").Set(iombps)
+	}
+	return rows.Err()
+}
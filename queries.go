@@ -0,0 +1,128 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+var queriesDir = flag.String("queries.dir", "", "Directory with query files in YAML (.yml, .yaml) or TOML (.toml) format, a connection references them by file name in query_sets.")
+
+// QueryFile is a file of the -queries.dir, its name without extension is the
+// name of the query set.
+type QueryFile struct {
+	Queries []Query `yaml:"queries" toml:"queries"`
+}
+
+// UnmarshalTOML accepts a plain column name as well as a metric definition.
+func (m *QueryMetric) UnmarshalTOML(data interface{}) error {
+	if column, ok := data.(string); ok {
+		m.Column = column
+		return nil
+	}
+	// decode the table again into the plain struct
+	var buf bytes.Buffer
+	if err := toml.NewEncoder(&buf).Encode(data); err != nil {
+		return err
+	}
+	type plain QueryMetric
+	md, err := toml.Decode(buf.String(), (*plain)(m))
+	if err != nil {
+		return err
+	}
+	return checkUndecoded(md, "")
+}
+
+// checkUndecoded returns an error for unknown keys, except the ones below the
+// prefix which are decoded by an UnmarshalTOML.
+func checkUndecoded(md toml.MetaData, prefix string) error {
+	unknown := []string{}
+	for _, key := range md.Undecoded() {
+		if prefix == "" || !strings.HasPrefix(key.String()+".", prefix) {
+			unknown = append(unknown, key.String())
+		}
+	}
+	if len(unknown) > 0 {
+		return fmt.Errorf("unknown keys %v", unknown)
+	}
+	return nil
+}
+
+// loadQuerySets reads all query files of the directory, keyed by query set name.
+func loadQuerySets(dir string) (map[string][]Query, error) {
+	sets := map[string][]Query{}
+	if dir == "" {
+		return sets, nil
+	}
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range files {
+		ext := filepath.Ext(f.Name())
+		if f.IsDir() || (ext != ".yml" && ext != ".yaml" && ext != ".toml") {
+			continue
+		}
+		path := filepath.Join(dir, f.Name())
+		content, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		var file QueryFile
+		// TOML has no line numbers, the queries are numbered instead
+		sources := func(j int) string { return fmt.Sprintf("%s (query %d)", path, j+1) }
+		keys := func(j int) keySources { return nil }
+		if ext == ".toml" {
+			var md toml.MetaData
+			md, err = toml.Decode(string(content), &file)
+			if err == nil {
+				err = checkUndecoded(md, "queries.metrics.")
+			}
+		} else {
+			var doc *yaml.Node
+			doc, err = decodeYAML(content, &file)
+			sources = func(j int) string { return source(path, nodeLine(doc, "queries", j)) }
+			keys = func(j int) keySources { return nodeKeySources(path, nodeAt(doc, "queries", j)) }
+		}
+		if err == nil {
+			err = expandEnv(&file)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", path, err)
+		}
+		for j := range file.Queries {
+			file.Queries[j].source = sources(j)
+			file.Queries[j].keys = keys(j)
+		}
+		name := strings.TrimSuffix(f.Name(), ext)
+		if _, ok := sets[name]; ok {
+			return nil, fmt.Errorf("%s: query set %q is defined twice", path, name)
+		}
+		sets[name] = file.Queries
+	}
+	return sets, nil
+}
+
+// resolveQuerySets adds the queries of the referenced query sets to the
+// connections and returns the references to unknown sets.
+func resolveQuerySets(configs *Configs, sets map[string][]Query) configErrors {
+	var problems configErrors
+	for i := range configs.Cfgs {
+		conf := &configs.Cfgs[i]
+		for _, name := range conf.QuerySets {
+			queries, ok := sets[name]
+			if !ok {
+				problems = append(problems, fmt.Sprintf("%s: unknown query set %q (-queries.dir=%q)", conf.source, name, *queriesDir))
+				continue
+			}
+			conf.Queries = append(conf.Queries, queries...)
+		}
+	}
+	return problems
+}
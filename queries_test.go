@@ -0,0 +1,145 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadQuerySets(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		// query names and their sources (relative to the directory) by set
+		want map[string][]string
+		// part of the error, empty if there is none
+		err string
+	}{
+		{
+			name: "YAML and TOML",
+			files: map[string]string{
+				"core.yml": `queries:
+- name: sessions
+  help: sessions
+  sql: select count(*) as n from v$session
+  metrics: [n]
+- name: locks
+  help: locks
+  sql: select count(*) as n from v$lock
+  metrics:
+  - column: n
+    name: oracledb_locks
+    type: gauge
+`,
+				"billing.toml": `[[queries]]
+name = "invoices"
+help = "invoices"
+sql = "select count(*) as n from invoices"
+metrics = [ "n" ]
+
+[[queries]]
+name = "calls"
+help = "calls"
+sql = "select calls, elapsed from calls"
+[[queries.metrics]]
+column = "calls"
+name = "billing_calls_total"
+type = "counter"
+`,
+				"README.md": "not a query file",
+			},
+			want: map[string][]string{
+				"core":    {"sessions core.yml:2", "locks core.yml:6"},
+				"billing": {"invoices billing.toml (query 1)", "calls billing.toml (query 2)"},
+			},
+		},
+		{
+			name:  "unknown TOML key of a query",
+			files: map[string]string{"app.toml": "[[queries]]\nname = \"q\"\nsqll = \"select 1 from dual\"\n"},
+			err:   "unknown keys [queries.sqll]",
+		},
+		{
+			name:  "unknown TOML key of a metric",
+			files: map[string]string{"app.toml": "[[queries]]\nname = \"q\"\n[[queries.metrics]]\ncolumn = \"n\"\ntyp = \"counter\"\n"},
+			err:   "unknown keys [typ]",
+		},
+		{
+			name:  "unknown YAML key",
+			files: map[string]string{"app.yaml": "queries:\n- name: q\n  sqll: select 1 from dual\n"},
+			err:   "field sqll not found",
+		},
+		{
+			name: "same set name with two extensions",
+			files: map[string]string{
+				"app.toml": "[[queries]]\nname = \"q\"\n",
+				"app.yml":  "queries:\n- name: q\n",
+			},
+			err: `query set "app" is defined twice`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "queries")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(dir)
+			for name, content := range tt.files {
+				if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0600); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			sets, err := loadQuerySets(dir)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("got error %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(sets) != len(tt.want) {
+				t.Errorf("got %d sets, want %d", len(sets), len(tt.want))
+			}
+			for name, want := range tt.want {
+				got := []string{}
+				for _, query := range sets[name] {
+					got = append(got, query.Name+" "+strings.TrimPrefix(query.source, dir+string(filepath.Separator)))
+				}
+				if strings.Join(got, ", ") != strings.Join(want, ", ") {
+					t.Errorf("set %s: got %v, want %v", name, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestResolveQuerySets(t *testing.T) {
+	sets := map[string][]Query{
+		"core":    {{Name: "sessions"}, {Name: "locks"}},
+		"billing": {{Name: "invoices"}},
+	}
+	conf := Configs{Cfgs: []Config{
+		{Database: "a", Queries: []Query{{Name: "own"}}, QuerySets: []string{"billing", "core"}},
+		{Database: "b", QuerySets: []string{"core", "nosuch"}, source: "oracle.conf:7"},
+	}}
+
+	problems := resolveQuerySets(&conf, sets)
+	want := []string{"own,invoices,sessions,locks", "sessions,locks"}
+	for i, c := range conf.Cfgs {
+		names := []string{}
+		for _, query := range c.Queries {
+			names = append(names, query.Name)
+		}
+		if got := strings.Join(names, ","); got != want[i] {
+			t.Errorf("%s: queries %s, want %s", c.Database, got, want[i])
+		}
+	}
+	if len(problems) != 1 || !strings.HasPrefix(problems[0], `oracle.conf:7: unknown query set "nosuch"`) {
+		t.Errorf("problems %q, want the unknown set nosuch of b", problems)
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+)
+
+var (
+	configReloadSuccess = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: exporter,
+		Name:      "config_last_reload_successful",
+		Help:      "Whether the last configuration reload attempt was successful.",
+	})
+	configReloadTime = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: exporter,
+		Name:      "config_last_reload_success_timestamp_seconds",
+		Help:      "Timestamp of the last successful configuration reload.",
+	})
+	// serializes reloads
+	reloadMu sync.Mutex
+)
+
+// currentConfig returns the configuration in use.
+func currentConfig() Configs {
+	configMu.RLock()
+	defer configMu.RUnlock()
+	return config
+}
+
+// setConfig replaces the configuration in use.
+func setConfig(conf Configs) {
+	configMu.Lock()
+	config = conf
+	configMu.Unlock()
+
+	configReloadSuccess.Set(1)
+	configReloadTime.Set(float64(time.Now().Unix()))
+}
+
+// reloadConfig reads the config file again and swaps it in. The cached scrape
+// handlers are dropped so the next scrape builds them from the new connections
+// and queries, background collectors and cached query results start over.
+// On error the old configuration stays in use.
+func reloadConfig() error {
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+
+	conf, err := readConfig(*configFile)
+	if err != nil {
+		log.Errorln("error reloading config: ", err)
+		configReloadSuccess.Set(0)
+		return err
+	}
+	old := currentConfig()
+	setConfig(conf)
+
+	resetHandlers()
+	stopBackgroundJobs()
+	stopQueryJobs()
+	dropStalePools(old.Cfgs, conf.Cfgs)
+
+	log.Infoln("Config reloaded: ", *configFile)
+	return nil
+}
+
+// ReloadHandler reloads the configuration on POST /-/reload.
+func ReloadHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Only POST requests allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if err := reloadConfig(); err != nil {
+		http.Error(w, "failed to reload config: "+err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// watchReloadSignal reloads the configuration on SIGHUP.
+func watchReloadSignal() {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		for range hup {
+			reloadConfig()
+		}
+	}()
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestReloadConfig(t *testing.T) {
+	defer setConfig(currentConfig())
+	defer func(v string) { *configFile = v }(*configFile)
+	defer func(v string) { *queriesDir = v }(*queriesDir)
+	dir, err := ioutil.TempDir("", "reload")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	*configFile = filepath.Join(dir, "oracle.conf")
+	*queriesDir = ""
+	write := func(content string) {
+		if err := ioutil.WriteFile(*configFile, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	addFakeDB("reload_kept", &fakeDB{results: upResults()})
+	addFakeDB("reload_changed", &fakeDB{results: upResults()})
+	write(`connections:
+- database: reload_kept
+  dsn: reload_kept
+  driver: fake
+- database: reload_changed
+  dsn: reload_changed
+  driver: fake
+  max_open_conns: 2
+`)
+	conf, err := readConfig(*configFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	setConfig(conf)
+	configs := map[string]*Config{}
+	for i := range conf.Cfgs {
+		c := &conf.Cfgs[i]
+		defer dropPool(c)
+		if _, err := openPool(c); err != nil {
+			t.Fatal(err)
+		}
+		configs[c.Database] = c
+	}
+	handlersMu.Lock()
+	handlers["/metrics"] = http.NotFoundHandler()
+	handlersMu.Unlock()
+
+	write(`connections:
+- database: reload_kept
+  dsn: reload_kept
+  driver: fake
+- database: reload_changed
+  dsn: reload_changed
+  driver: fake
+  max_open_conns: 4
+`)
+	if err := reloadConfig(); err != nil {
+		t.Fatalf("reload failed: %v", err)
+	}
+	if got := currentConfig().Cfgs[1].MaxOpenConns; got != 4 {
+		t.Errorf("max_open_conns is %d after the reload, want 4", got)
+	}
+	handlersMu.Lock()
+	if len(handlers) != 0 {
+		t.Errorf("%d handlers kept after the reload, want 0", len(handlers))
+	}
+	handlersMu.Unlock()
+	poolsMu.Lock()
+	_, kept := pools[configs["reload_kept"].poolKey()]
+	_, changed := pools[configs["reload_changed"].poolKey()]
+	poolsMu.Unlock()
+	if !kept {
+		t.Errorf("the pool of the unchanged connection was dropped")
+	}
+	if changed {
+		t.Errorf("the pool of the changed connection was kept")
+	}
+	if got := testutil.ToFloat64(configReloadSuccess); got != 1 {
+		t.Errorf("config_last_reload_successful = %v, want 1", got)
+	}
+
+	write("connections:\n- database: [\n")
+	if err := reloadConfig(); err == nil {
+		t.Fatalf("reload of an invalid file succeeded")
+	}
+	if got := currentConfig().Cfgs[1].MaxOpenConns; got != 4 {
+		t.Errorf("max_open_conns is %d after a failed reload, want the old 4", got)
+	}
+	if got := testutil.ToFloat64(configReloadSuccess); got != 0 {
+		t.Errorf("config_last_reload_successful = %v after a failed reload, want 0", got)
+	}
+}
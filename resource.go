@@ -0,0 +1,36 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+)
+
+// ScrapeResourceLimit collects the utilization and limits of the resources
+// from v$resource_limit, e.g. processes (ORA-00020) and sessions (ORA-00018).
+func (e *Exporter) ScrapeResourceLimit(ctx context.Context, config *Config) error {
+	db := config.db
+
+	// limit_value is a string, UNLIMITED has no limit
+	rows, err := db.QueryContext(ctx, `select resource_name, current_utilization, max_utilization,
+                                 case when trim(limit_value) = 'UNLIMITED' then NULL else to_number(limit_value) end
+                                 from v$resource_limit`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name string
+		var current float64
+		var max float64
+		var limit sql.NullFloat64
+		if err := rows.Scan(&name, &current, &max, &limit); err != nil {
+			return err
+		}
+		e.resourceCurrent.WithLabelValues(config.Database, config.Instance, name).Set(current)
+		e.resourceMax.WithLabelValues(config.Database, config.Instance, name).Set(max)
+		if limit.Valid {
+			e.resourceLimit.WithLabelValues(config.Database, config.Instance, name).Set(limit.Float64)
+		}
+	}
+	return rows.Err()
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// backgroundResult is the outcome of the last background run of a collector.
+type backgroundResult struct {
+	// samples of the last successful run
+	metrics     []prometheus.Metric
+	lastSuccess time.Time
+	duration    time.Duration
+	err         error
+	done        bool
+}
+
+// backgroundJob runs a collector for one database on its own interval.
+type backgroundJob struct {
+	mu     sync.Mutex
+	result backgroundResult
+	// stops the runs, see stopBackgroundJobs
+	cancel context.CancelFunc
+}
+
+var (
+	backgroundJobs   = map[string]*backgroundJob{}
+	backgroundJobsMu sync.Mutex
+)
+
+// cachedResult returns the cached result of the collector for the config,
+// the background runs are started on first use.
+func cachedResult(c *collector, config *Config) backgroundResult {
+	key := config.poolKey() + "/" + c.name
+
+	backgroundJobsMu.Lock()
+	job, ok := backgroundJobs[key]
+	if !ok {
+		ctx, cancel := context.WithCancel(context.Background())
+		job = &backgroundJob{cancel: cancel}
+		backgroundJobs[key] = job
+		// own copy, the db of the exporter's config is reassigned on every scrape
+		go job.loop(ctx, c, *config, *c.intervalFlag)
+	}
+	backgroundJobsMu.Unlock()
+
+	job.mu.Lock()
+	defer job.mu.Unlock()
+	return job.result
+}
+
+func (job *backgroundJob) loop(ctx context.Context, c *collector, config Config, interval time.Duration) {
+	for {
+		job.run(ctx, c, &config, interval)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+	}
+}
+
+// stopBackgroundJobs stops all background runs, they are started again by the next scrape.
+func stopBackgroundJobs() {
+	backgroundJobsMu.Lock()
+	defer backgroundJobsMu.Unlock()
+
+	for _, job := range backgroundJobs {
+		job.cancel()
+	}
+	backgroundJobs = map[string]*backgroundJob{}
+}
+
+// run scrapes the collector into a private exporter and caches the samples.
+// A run may take up to the interval, a failed run keeps the previous samples.
+func (job *backgroundJob) run(ctx context.Context, c *collector, config *Config, interval time.Duration) {
+	ctx, cancel := context.WithTimeout(ctx, interval)
+	defer cancel()
+
+	begun := time.Now()
+	metrics, err := func() ([]prometheus.Metric, error) {
+		if !c.local {
+			db, err := openPool(config)
+			if err != nil {
+				return nil, err
+			}
+			if err := checkPool(ctx, db); err != nil {
+				return nil, config.redact(err)
+			}
+			config.db = db
+			if config.open, config.mounted, config.cdb, err = inspect(ctx, db); err != nil {
+				return nil, config.redact(err)
+			}
+			if c.needsOpen && !config.open {
+				// nothing to collect on a started or mounted instance
+				return nil, nil
+			}
+			if c.needsMount && !config.mounted {
+				// nothing to collect on ASM or a started instance
+				return nil, nil
+			}
+		}
+		e := NewExporter()
+		if err := c.scrape(e, ctx, config); err != nil {
+			return nil, err
+		}
+		return snapshot(c.metrics(e)), nil
+	}()
+
+	logger := dbLogger(config).With("collector", c.name)
+	if err != nil {
+		logger.Errorln("background collector failed: ", err)
+	} else {
+		logger.Debugln("background collector finished in ", time.Since(begun))
+	}
+
+	job.mu.Lock()
+	defer job.mu.Unlock()
+	job.result.done = true
+	job.result.duration = time.Since(begun)
+	job.result.err = err
+	if err == nil {
+		job.result.metrics = metrics
+		job.result.lastSuccess = time.Now()
+	}
+}
+
+// snapshot returns the current samples of the metric vectors.
+func snapshot(vecs []metricVec) []prometheus.Metric {
+	ch := make(chan prometheus.Metric)
+	go func() {
+		for _, vec := range vecs {
+			vec.Collect(ch)
+		}
+		close(ch)
+	}()
+
+	metrics := []prometheus.Metric{}
+	for metric := range ch {
+		metrics = append(metrics, metric)
+	}
+	return metrics
+}
+
+// collectBackground sends the cached results of the enabled background collectors.
+func (e *Exporter) collectBackground(ch chan<- prometheus.Metric) {
+	for _, config := range e.configs {
+		for _, c := range collectors {
+			if !c.background() || !e.enabled(c, config) {
+				continue
+			}
+			result := cachedResult(c, config)
+			if !result.done {
+				continue
+			}
+			if result.err != nil {
+				e.collectorUp.WithLabelValues(c.name, config.Database, config.Instance).Set(0)
+			} else {
+				e.collectorUp.WithLabelValues(c.name, config.Database, config.Instance).Set(1)
+			}
+			e.collectorTime.WithLabelValues(c.name, config.Database, config.Instance).Set(result.duration.Seconds())
+			if !result.lastSuccess.IsZero() {
+				e.lastSuccess.WithLabelValues(c.name, config.Database, config.Instance).Set(float64(result.lastSuccess.Unix()))
+			}
+			for _, metric := range result.metrics {
+				ch <- metric
+			}
+		}
+	}
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+)
+
+var (
+	scrapeConcurrency   = flag.Int("scrape.max-concurrency", 8, "Maximum number of collectors running in parallel during a scrape.")
+	scrapeTimeout       = flag.Duration("scrape.timeout", 0, "Scrape deadline if Prometheus sends no X-Prometheus-Scrape-Timeout-Seconds header, 0 means no deadline.")
+	queryTimeout        = flag.Duration("query.timeout", 30*time.Second, "Default timeout of the queries of a collector, 0 means no timeout (can be overridden per custom query).")
+	scrapeTimeoutOffset = flag.Duration("scrape.timeout-offset", 500*time.Millisecond, "Subtracted from the Prometheus scrape timeout to leave time for sending the response.")
+)
+
+// scrapeJob is one collector run against one database.
+type scrapeJob struct {
+	name   string
+	config *Config
+	// scrape runs the collector into a sink of its own and returns the
+	// function which adds the samples to the scrape, see scrapeSamples
+	scrape func(context.Context, *Config) (func(), error)
+	// local jobs don't use a connection of the pool
+	local   bool
+	timeout time.Duration
+}
+
+// jobResult is the outcome of a job, recorded by the scrape which started it.
+type jobResult struct {
+	// index of the job in the scrape
+	index    int
+	job      scrapeJob
+	add      func()
+	err      error
+	duration time.Duration
+	timedOut bool
+}
+
+// scrapeSamples are the samples of the jobs of one scrape. Only the results
+// which arrive before the deadline are added, jobs which are still running
+// then write into sinks which are dropped.
+type scrapeSamples struct {
+	metrics []prometheus.Metric
+	custom  *customSamples
+}
+
+func newScrapeSamples() *scrapeSamples {
+	return &scrapeSamples{custom: newCustomSamples()}
+}
+
+func (s *scrapeSamples) collect(ch chan<- prometheus.Metric) {
+	for _, metric := range s.metrics {
+		ch <- metric
+	}
+	s.custom.collect(ch)
+}
+
+// newSink returns the exporter a job of a scrape writes its samples into.
+func (e *Exporter) newSink() *Exporter {
+	sink := NewExporter()
+	sink.lastIp = e.lastIp
+	return sink
+}
+
+// scrapeTimeoutFor returns the deadline for a scrape request, derived from the
+// timeout Prometheus announces in the request header.
+func scrapeTimeoutFor(r *http.Request) time.Duration {
+	v := r.Header.Get("X-Prometheus-Scrape-Timeout-Seconds")
+	if v == "" {
+		return *scrapeTimeout
+	}
+	seconds, err := strconv.ParseFloat(v, 64)
+	if err != nil || seconds <= 0 {
+		log.Warnln("invalid X-Prometheus-Scrape-Timeout-Seconds header: " + v)
+		return *scrapeTimeout
+	}
+	timeout := time.Duration(seconds * float64(time.Second))
+	if timeout > *scrapeTimeoutOffset {
+		timeout -= *scrapeTimeoutOffset
+	}
+	return timeout
+}
+
+// scrapeContext returns the context for the current scrape, cancelled at the deadline.
+func (e *Exporter) scrapeContext() (context.Context, context.CancelFunc) {
+	if e.timeout > 0 {
+		return context.WithTimeout(context.Background(), e.timeout)
+	}
+	return context.WithCancel(context.Background())
+}
+
+// scrape runs the jobs on a bounded pool of workers until all of them are done
+// or the context expires. Jobs which did not start in time are skipped, running
+// ones get their queries cancelled through the context, their results are
+// dropped. Both count as timed out.
+func (e *Exporter) scrape(ctx context.Context, jobs []scrapeJob) {
+	queue := make(chan int, len(jobs))
+	for i := range jobs {
+		queue <- i
+	}
+	close(queue)
+
+	workers := *scrapeConcurrency
+	if workers < 1 {
+		workers = 1
+	}
+	// buffered, so workers which finish after the deadline don't block
+	results := make(chan jobResult, len(jobs))
+	for i := 0; i < workers; i++ {
+		go func() {
+			for i := range queue {
+				if ctx.Err() != nil {
+					continue
+				}
+				r := e.run(ctx, jobs[i])
+				r.index = i
+				results <- r
+			}
+		}()
+	}
+
+	// don't wait for drivers which ignore the cancellation
+	finished := make([]bool, len(jobs))
+	for range jobs {
+		select {
+		case r := <-results:
+			finished[r.index] = true
+			e.record(r)
+		case <-ctx.Done():
+			log.Warnln("scrape deadline exceeded, returning partial results")
+			for i, job := range jobs {
+				if finished[i] {
+					continue
+				}
+				dbLogger(job.config).With("collector", job.name).Warnln("collector did not finish before the scrape deadline")
+				e.timeouts.WithLabelValues(job.name, job.config.Database, job.config.Instance).Inc()
+				e.collectorUp.WithLabelValues(job.name, job.config.Database, job.config.Instance).Set(0)
+				e.scrapeFailed(job.config)
+			}
+			return
+		}
+	}
+}
+
+// run executes a single job within its query timeout.
+func (e *Exporter) run(ctx context.Context, job scrapeJob) jobResult {
+	if !job.local {
+		release, err := acquireJobSlot(ctx, job.config)
+		if err != nil {
+			return jobResult{job: job, err: err, timedOut: true}
+		}
+		defer release()
+	}
+	if job.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, job.timeout)
+		defer cancel()
+	}
+	begun := time.Now()
+	add, err := job.scrape(ctx, job.config)
+	return jobResult{job: job, add: add, err: err, duration: time.Since(begun),
+		timedOut: ctx.Err() == context.DeadlineExceeded}
+}
+
+// record adds the samples of a finished job to the scrape and records its outcome.
+func (e *Exporter) record(r jobResult) {
+	job := r.job
+	e.collectorTime.WithLabelValues(job.name, job.config.Database, job.config.Instance).Set(r.duration.Seconds())
+
+	logger := dbLogger(job.config).With("collector", job.name)
+	if r.timedOut {
+		logger.Warnln("collector timed out after ", r.duration)
+		e.timeouts.WithLabelValues(job.name, job.config.Database, job.config.Instance).Inc()
+	}
+	if r.add != nil {
+		r.add()
+	}
+	if r.err != nil {
+		logger.Errorln("collector failed: ", job.config.redact(r.err))
+		e.collectorUp.WithLabelValues(job.name, job.config.Database, job.config.Instance).Set(0)
+		e.scrapeFailed(job.config)
+		return
+	}
+	e.collectorUp.WithLabelValues(job.name, job.config.Database, job.config.Instance).Set(1)
+	e.lastSuccess.WithLabelValues(job.name, job.config.Database, job.config.Instance).Set(float64(time.Now().Unix()))
+}
+
+// scrapeFailed marks the current scrape of a database as failed.
+func (e *Exporter) scrapeFailed(config *Config) {
+	e.error.WithLabelValues(config.Database, config.Instance).Set(1)
+	e.scrapeErrors.WithLabelValues(config.Database, config.Instance).Inc()
+}
+
+// dbLogger returns a logger tagged with the database and instance of the config.
+func dbLogger(config *Config) log.Logger {
+	return log.With("database", config.Database).With("dbinstance", config.Instance)
+}
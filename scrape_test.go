@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestScrapeDropsLateResults(t *testing.T) {
+	config := &Config{Database: "scrape_late", Instance: "scrape_late"}
+	release := make(chan struct{})
+	finished := make(chan struct{})
+	added := false
+	jobs := []scrapeJob{{name: "slow", config: config, local: true,
+		scrape: func(ctx context.Context, config *Config) (func(), error) {
+			defer close(finished)
+			<-release
+			return func() { added = true }, nil
+		}}}
+
+	e := NewExporter()
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	e.scrape(ctx, jobs)
+	close(release)
+	<-finished
+	// give a late result the chance to be recorded
+	time.Sleep(10 * time.Millisecond)
+
+	if added {
+		t.Errorf("the samples of a job which finished after the deadline were added")
+	}
+	if got := testutil.CollectAndCount(e.collectorTime); got != 0 {
+		t.Errorf("collector_duration_seconds has %d samples of a late job, want 0", got)
+	}
+}
+
+func TestScrapeCountsUnfinishedJobs(t *testing.T) {
+	defer func(v int) { *scrapeConcurrency = v }(*scrapeConcurrency)
+	*scrapeConcurrency = 1
+	config := &Config{Database: "scrape_queued", Instance: "scrape_queued"}
+	release := make(chan struct{})
+	defer close(release)
+	slow := func(ctx context.Context, config *Config) (func(), error) {
+		<-release
+		return nil, nil
+	}
+	// one worker, the second job is still queued at the deadline
+	jobs := []scrapeJob{
+		{name: "running", config: config, local: true, scrape: slow},
+		{name: "queued", config: config, local: true, scrape: slow},
+	}
+
+	e := NewExporter()
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	e.scrape(ctx, jobs)
+
+	for _, name := range []string{"running", "queued"} {
+		if got := testutil.ToFloat64(e.timeouts.WithLabelValues(name, config.Database, config.Instance)); got != 1 {
+			t.Errorf("%s: collector_timeouts_total = %v, want 1", name, got)
+		}
+		if got := testutil.ToFloat64(e.collectorUp.WithLabelValues(name, config.Database, config.Instance)); got != 0 {
+			t.Errorf("%s: collector_success = %v, want 0", name, got)
+		}
+	}
+	if got := testutil.ToFloat64(e.error.WithLabelValues(config.Database, config.Instance)); got != 1 {
+		t.Errorf("last_scrape_error = %v, want 1", got)
+	}
+}
+
+func TestHangingJobsLeaveConnectionForConnect(t *testing.T) {
+	wait := make(chan struct{})
+	defer close(wait)
+	config := addFakeDB("scrape_hang", &fakeDB{results: upResults(
+		fakeResult{match: "from hanging", columns: []string{"VALUE"}, rows: nil, wait: wait},
+	)})
+	config.MaxOpenConns = 2
+	config.MaxIdleConns = 2
+	defer dropPool(config)
+	for _, name := range []string{"a", "b", "c"} {
+		config.Queries = append(config.Queries, Query{Name: "hanging_" + name, Help: "hanging",
+			Sql: "select value from hanging", Metrics: []QueryMetric{{Column: "value"}}})
+	}
+
+	e := NewExporter()
+	e.configs = []*Config{config}
+	e.collect = map[string]bool{}
+	for i := 0; i < 2; i++ {
+		e.timeout = 200 * time.Millisecond
+		testutil.CollectAndCount(e)
+		if got := testutil.ToFloat64(e.up.WithLabelValues(config.Database, config.Instance)); got != 1 {
+			t.Fatalf("scrape %d: oracledb_up = %v while earlier queries hang, want 1", i+1, got)
+		}
+	}
+}
@@ -200,7 +200,7 @@ type exporterService struct {
 }
 
 func (s *exporterService) Execute(args []string, r <-chan svc.ChangeRequest, changes chan<- svc.Status) (ssec bool, errno uint32) {
-	const cmdsAccepted = svc.AcceptStop | svc.AcceptShutdown | svc.AcceptPauseAndContinue
+	const cmdsAccepted = svc.AcceptStop | svc.AcceptShutdown | svc.AcceptPauseAndContinue | svc.AcceptParamChange
 	changes <- svc.Status{State: svc.StartPending}
 	changes <- svc.Status{State: svc.Running, Accepts: cmdsAccepted}
 	winSvcRunning = true
@@ -210,6 +210,10 @@ loop:
 		switch c.Cmd {
 		case svc.Interrogate:
 			changes <- c.CurrentStatus
+		case svc.ParamChange:
+			// sc control <name> paramchange reloads the config
+			reloadConfig()
+			changes <- c.CurrentStatus
 		case svc.Stop, svc.Shutdown:
 			break loop
 		default:
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+)
+
+var (
+	sysstatNames = listFlag{"user commits", "user rollbacks", "parse count (total)", "execute count",
+		"user calls", "session logical reads", "physical reads", "physical writes", "redo size"}
+	sysmetricNames = listFlag{"Physical Read Total IO Requests Per Sec", "Physical Read Total Bytes Per Sec",
+		"Physical Write Total IO Requests Per Sec", "Physical Write Total Bytes Per Sec",
+		"Host CPU Utilization (%)", "Average Active Sessions"}
+	sysmetricGroup = choiceFlag{value: "long", choices: []string{"long", "short"}}
+)
+
+// group_id of v$sysmetric
+var sysmetricGroupIDs = map[string]int{
+	"long":  2, // System Metrics Long Duration, 60s
+	"short": 3, // System Metrics Short Duration, 15s
+}
+
+func init() {
+	flag.Var(&sysstatNames, "collector.sysstat.names", "Comma separated names of the statistics of v$sysstat to export.")
+	flag.Var(&sysmetricNames, "collector.sysmetric.names", "Comma separated names of the metrics of v$sysmetric to export.")
+	flag.Var(&sysmetricGroup, "collector.sysmetric.group", "Interval of the metrics of v$sysmetric, long (60s) or short (15s).")
+}
+
+// ScrapeSysstat collects activity metrics from the v$sysstat view.
+func (e *Exporter) ScrapeSysstat(ctx context.Context, config *Config) error {
+	if len(sysstatNames) == 0 {
+		return nil
+	}
+	db := config.db
+
+	// statistic# differs between the versions, the names don't
+	binds, args := bindList(1, sysstatNames)
+	rows, err := db.QueryContext(ctx, `SELECT name, value FROM v$sysstat
+                                      WHERE name in (`+binds+`)`, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name string
+		var value float64
+		if err := rows.Scan(&name, &value); err != nil {
+			return err
+		}
+		name = cleanName(name)
+		e.sysstat.WithLabelValues(config.Database, config.Instance, name).Set(value)
+	}
+	return rows.Err()
+}
+
+// ScrapeSysmetric collects the metrics of the last interval from the v$sysmetric view.
+func (e *Exporter) ScrapeSysmetric(ctx context.Context, config *Config) error {
+	if len(sysmetricNames) == 0 {
+		return nil
+	}
+	db := config.db
+
+	binds, args := bindList(2, sysmetricNames)
+	args = append([]interface{}{sysmetricGroupIDs[sysmetricGroup.value]}, args...)
+	query := `select 0, NULL, metric_name, value from v$sysmetric
+                 where group_id = :1 and metric_name in (` + binds + `)`
+	// v$con_sysmetric only has the long interval, the short one is read for the whole CDB
+	if config.cdb && sysmetricGroup.value == "long" {
+		binds, args = bindList(1, sysmetricNames)
+		query = `select m.con_id, c.name, m.metric_name, m.value
+                 from v$con_sysmetric m LEFT JOIN v$containers c ON c.con_id = m.con_id
+                 where m.metric_name in (` + binds + `)`
+	}
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var conID string
+		var pdb sql.NullString
+		var name string
+		var value float64
+		if err := rows.Scan(&conID, &pdb, &name, &value); err != nil {
+			return err
+		}
+		name = cleanName(name)
+		e.sysmetric.WithLabelValues(config.Database, config.Instance, conID, pdb.String, name).Set(value)
+	}
+	return rows.Err()
+}
+
+// ScrapeCache collects the cache hit ratios of the last minute from the v$sysmetric view.
+func (e *Exporter) ScrapeCache(ctx context.Context, config *Config) error {
+	db := config.db
+
+	rows, err := db.QueryContext(ctx, `select metric_name,value
+                                 from v$sysmetric
+                                 where group_id=2 and metric_name in ('Buffer Cache Hit Ratio','Cursor Cache Hit Ratio',
+                                                                      'Library Cache Hit Ratio','Row Cache Hit Ratio')`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name string
+		var value float64
+		if err := rows.Scan(&name, &value); err != nil {
+			return err
+		}
+		name = cleanName(name)
+		e.cache.WithLabelValues(config.Database, config.Instance, name).Set(value)
+	}
+	return rows.Err()
+}
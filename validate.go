@@ -0,0 +1,356 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/model"
+	"gopkg.in/yaml.v3"
+)
+
+var configCheck = flag.Bool("config.check", false, "Check the config file and the query files, print all problems and exit, non-zero if there are any.")
+
+// configErrors are all problems found in the configuration.
+type configErrors []string
+
+func (errs configErrors) Error() string {
+	return strings.Join(errs, "\n")
+}
+
+// decodeYAML decodes the content strictly, unknown keys are errors, and
+// returns the document node for the line numbers of the error messages.
+func decodeYAML(content []byte, out interface{}) (*yaml.Node, error) {
+	dec := yaml.NewDecoder(bytes.NewReader(content))
+	dec.KnownFields(true)
+	if err := dec.Decode(out); err != nil && err != io.EOF {
+		return nil, err
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(content, &doc); err != nil {
+		return nil, err
+	}
+	return &doc, nil
+}
+
+// checkKeys returns an error for the keys of the mapping node which are not
+// known. It is a TypeError, so the decoder goes on and reports all problems.
+func checkKeys(node *yaml.Node, known ...string) error {
+	if node.Kind != yaml.MappingNode {
+		return nil
+	}
+	var problems []string
+	for i := 0; i < len(node.Content); i += 2 {
+		key := node.Content[i]
+		found := false
+		for _, k := range known {
+			found = found || key.Value == k
+		}
+		if !found {
+			problems = append(problems, fmt.Sprintf("line %d: field %s not found in type main.QueryMetric", key.Line, key.Value))
+		}
+	}
+	if len(problems) > 0 {
+		return &yaml.TypeError{Errors: problems}
+	}
+	return nil
+}
+
+// nodeLine returns the line of the value at the path of mapping keys and
+// sequence indexes, 0 if there is no such value.
+func nodeLine(doc *yaml.Node, path ...interface{}) int {
+	if node := nodeAt(doc, path...); node != nil {
+		return node.Line
+	}
+	return 0
+}
+
+// nodeAt returns the value at the path of mapping keys and sequence indexes,
+// nil if there is no such value.
+func nodeAt(doc *yaml.Node, path ...interface{}) *yaml.Node {
+	if doc == nil || len(doc.Content) == 0 {
+		return nil
+	}
+	node := doc.Content[0]
+	for _, p := range path {
+		var next *yaml.Node
+		switch p := p.(type) {
+		case string:
+			for i := 0; node.Kind == yaml.MappingNode && i+1 < len(node.Content); i += 2 {
+				if node.Content[i].Value == p {
+					next = node.Content[i+1]
+				}
+			}
+		case int:
+			if node.Kind == yaml.SequenceNode && p < len(node.Content) {
+				next = node.Content[p]
+			}
+		}
+		if next == nil {
+			return nil
+		}
+		node = next
+	}
+	return node
+}
+
+// keySources are the sources of the keys of a definition by their path,
+// e.g. "tns_admin" or "metrics.1.name".
+type keySources map[string]string
+
+// nodeKeySources returns the sources of all keys below the node.
+func nodeKeySources(file string, node *yaml.Node) keySources {
+	keys := keySources{}
+	var walk func(prefix string, node *yaml.Node)
+	walk = func(prefix string, node *yaml.Node) {
+		switch node.Kind {
+		case yaml.MappingNode:
+			for i := 0; i+1 < len(node.Content); i += 2 {
+				key := prefix + node.Content[i].Value
+				keys[key] = source(file, node.Content[i].Line)
+				walk(key+".", node.Content[i+1])
+			}
+		case yaml.SequenceNode:
+			for i, item := range node.Content {
+				key := prefix + strconv.Itoa(i)
+				keys[key] = source(file, item.Line)
+				walk(key+".", item)
+			}
+		}
+	}
+	if node != nil {
+		walk("", node)
+	}
+	return keys
+}
+
+// of returns the source of the key, of the closest enclosing key if it is
+// missing, and the source of the definition if there is none.
+func (keys keySources) of(key string, def string) string {
+	for key != "" {
+		if s, ok := keys[key]; ok {
+			return s
+		}
+		i := strings.LastIndex(key, ".")
+		if i < 0 {
+			break
+		}
+		key = key[:i]
+	}
+	return def
+}
+
+// source formats the position of a definition for error messages.
+func source(file string, line int) string {
+	if line == 0 {
+		return file
+	}
+	return fmt.Sprintf("%s:%d", file, line)
+}
+
+// reservedMetricNames are the metrics which Prometheus adds to every target.
+var reservedMetricNames = []string{"up", "scrape_duration_seconds", "scrape_samples_scraped",
+	"scrape_samples_post_metric_relabeling", "scrape_series_added"}
+
+var descName = regexp.MustCompile(`fqName: "([^"]*)"`)
+
+// builtinMetricNames returns the names of the metrics of the exporter and its
+// collectors, a custom metric must not reuse them.
+func builtinMetricNames() map[string]bool {
+	e := NewExporter()
+	e.custom = nil
+	ch := make(chan *prometheus.Desc)
+	go func() {
+		e.Describe(ch)
+		close(ch)
+	}()
+	names := map[string]bool{}
+	for desc := range ch {
+		// the name of a descriptor is only available in its string form
+		if m := descName.FindStringSubmatch(desc.String()); m != nil {
+			names[m[1]] = true
+		}
+	}
+	for _, name := range reservedMetricNames {
+		names[name] = true
+	}
+	return names
+}
+
+// validateConfig checks the rules which the YAML decoder can't, and which
+// would otherwise only fail at scrape time. Paths which don't exist are
+// returned as warnings, see readConfig.
+func validateConfig(conf *Configs) (problems, warnings configErrors) {
+	problem := func(source string, format string, args ...interface{}) {
+		problems = append(problems, source+": "+fmt.Sprintf(format, args...))
+	}
+
+	// the first definition of a custom metric, all others must match it
+	type metricDef struct {
+		source    string
+		valueType string
+		labels    []string
+	}
+	metrics := map[string]metricDef{}
+	builtin := builtinMetricNames()
+	instances := map[string]string{}
+	// queries of a query set are checked once, not for every connection
+	checked := map[string]bool{}
+
+	for i := range conf.Cfgs {
+		c := &conf.Cfgs[i]
+		if c.Database == "" {
+			problem(c.source, "database is missing")
+		}
+		key := c.Database + "/" + c.Instance
+		if first, ok := instances[key]; ok {
+			problem(c.keys.of("instance", c.keys.of("database", c.source)), "database %s instance %s is already defined at %s", c.Database, c.Instance, first)
+		} else {
+			instances[key] = c.source
+		}
+		if c.dbDriver() == nil {
+			problem(c.keys.of("driver", c.source), "driver %q is not built in, available: %v", c.driverName(), dbDriverNames())
+		} else if c.Role != "" && !c.dbDriver().supportsRole(c.Role) {
+			problem(c.keys.of("role", c.source), "role %s is not supported by driver %s, supported: %v", c.Role, c.driverName(), c.dbDriver().roles)
+		}
+		for _, p := range c.validateCredentials() {
+			problem(c.source, "%s", p)
+		}
+		for name := range c.Collectors {
+			if lookupCollector(name) == nil {
+				problem(c.keys.of("collectors."+name, c.source), "unknown collector %q, available: %v", name, collectorNames())
+			}
+		}
+		if c.TnsAdmin != "" {
+			if _, err := os.Stat(c.TnsAdmin); err != nil {
+				warnings = append(warnings, fmt.Sprintf("%s: tns_admin: %v", c.keys.of("tns_admin", c.source), err))
+			}
+		}
+		for j, alert := range c.Alertlog {
+			if _, err := os.Stat(alert.File); err != nil {
+				warnings = append(warnings, fmt.Sprintf("%s: alertlog: %v", c.keys.of(fmt.Sprintf("alertlog.%d.file", j), c.source), err))
+			}
+		}
+
+		queries := map[string]string{}
+		for _, query := range c.Queries {
+			query := query
+			if first, ok := queries[query.Name]; ok {
+				problem(query.keys.of("name", query.source), "query %s is already defined at %s for database %s", query.Name, first, c.Database)
+			} else {
+				queries[query.Name] = query.keys.of("name", query.source)
+			}
+			if !checked[query.source] {
+				validateQuery(query, func(key string, format string, args ...interface{}) {
+					problem(query.keys.of(key, query.source), "query %s: %s", query.Name, fmt.Sprintf(format, args...))
+				})
+				checked[query.source] = true
+			}
+
+			for j, m := range query.Metrics {
+				metric := fmt.Sprintf("metrics.%d", j)
+				name := customFQName(query, m)
+				def := metricDef{source: query.keys.of(metric, query.source), valueType: m.Type, labels: customLabels(query, m)}
+				if def.valueType == "" {
+					def.valueType = typeGauge
+				}
+				first, ok := metrics[name]
+				if !ok {
+					metrics[name] = def
+					if builtin[name] {
+						problem(query.keys.of(metric+".name", def.source), "metric %s is a metric of the exporter", name)
+					}
+					continue
+				}
+				if first.source == def.source {
+					continue
+				}
+				if first.valueType != def.valueType {
+					problem(query.keys.of(metric+".type", def.source), "metric %s has type %s, but %s at %s", name, def.valueType, first.valueType, first.source)
+				}
+				// the samples are built with the labels of the first definition, in that order
+				if strings.Join(first.labels, ",") != strings.Join(def.labels, ",") {
+					problem(query.keys.of("labels", def.source), "metric %s has labels %v, but %v at %s", name, def.labels, first.labels, first.source)
+				}
+			}
+		}
+	}
+	return problems, warnings
+}
+
+// validateQuery checks a custom query on its own, each problem is reported
+// with the key of the query it is about.
+func validateQuery(query Query, problem func(key string, format string, args ...interface{})) {
+	if query.Name == "" {
+		problem("name", "name is missing")
+	}
+	if query.Help == "" {
+		problem("help", "help is missing")
+	}
+	if query.Sql == "" {
+		problem("sql", "sql is missing")
+	}
+	if len(query.Metrics) == 0 {
+		problem("metrics", "metrics are missing")
+	}
+
+	labels := map[string]bool{}
+	for i, label := range query.Labels {
+		key := fmt.Sprintf("labels.%d", i)
+		name := cleanName(label)
+		if !model.LabelName(name).IsValid() {
+			problem(key, "invalid label name %q", name)
+		}
+		if labels[name] || name == "database" || name == "dbinstance" || name == "rownum" || name == "metric" {
+			problem(key, "duplicate label %q", name)
+		}
+		labels[name] = true
+	}
+
+	for i, m := range query.Metrics {
+		key := fmt.Sprintf("metrics.%d", i)
+		if !m.typed() {
+			if m.Type != "" {
+				problem(key+".type", "column %s: a type needs a name", m.Column)
+			}
+			if m.Column == "" {
+				problem(key, "metric without column")
+			}
+			if name := customFQName(query, m); !model.IsValidMetricName(model.LabelValue(name)) {
+				problem("name", "invalid metric name %q", name)
+			}
+			continue
+		}
+		if !model.IsValidMetricName(model.LabelValue(m.Name)) {
+			problem(key+".name", "invalid metric name %q", m.Name)
+		}
+		switch m.Type {
+		case "", typeGauge, typeCounter, typeUntyped:
+			if m.Column == "" {
+				problem(key, "metric %s: column is missing", m.Name)
+			}
+		case typeHistogram:
+			if len(m.Buckets) == 0 || m.Count == "" || m.Sum == "" {
+				problem(key, "metric %s: a histogram needs buckets, count and sum", m.Name)
+			}
+		default:
+			problem(key+".type", "metric %s: unknown type %q", m.Name, m.Type)
+		}
+	}
+}
+
+// checkConfig validates the config for -config.check and returns the exit code.
+func checkConfig() int {
+	if _, err := readConfig(*configFile); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	fmt.Println(*configFile, "is valid")
+	return 0
+}
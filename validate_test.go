@@ -0,0 +1,184 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestValidateConfig(t *testing.T) {
+	defer func(v bool) { *configCheck = v }(*configCheck)
+	dir, err := ioutil.TempDir("", "validate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer func(v string) { *queriesDir = v }(*queriesDir)
+	*queriesDir = filepath.Join(dir, "queries")
+	if err := os.Mkdir(*queriesDir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(dir, "config.yml")
+
+	tests := []struct {
+		name        string
+		config      string
+		configCheck bool
+		// problems as <line>: <message>, none if the config is valid
+		want []string
+	}{
+		{
+			name: "valid",
+			config: `connections:
+- database: db
+  dsn: db
+  driver: fake
+`,
+		},
+		{
+			name: "unknown collector at its key",
+			config: `connections:
+- database: db
+  dsn: db
+  driver: fake
+  collectors:
+    session: true
+    nosuch: true
+`,
+			want: []string{`7: unknown collector "nosuch"`},
+		},
+		{
+			name: "name of an exporter metric",
+			config: `connections:
+- database: db
+  dsn: db
+  driver: fake
+  queries:
+  - name: q
+    help: q
+    sql: select 1 as v from dual
+    metrics:
+    - column: v
+      name: oracledb_up
+`,
+			want: []string{"11: metric oracledb_up is a metric of the exporter"},
+		},
+		{
+			name: "name which Prometheus adds to the target",
+			config: `connections:
+- database: db
+  dsn: db
+  driver: fake
+  queries:
+  - name: q
+    help: q
+    sql: select 1 as v from dual
+    metrics:
+    - column: v
+      name: up
+`,
+			want: []string{"11: metric up is a metric of the exporter"},
+		},
+		{
+			name: "typed metric with the name of an untyped one",
+			config: `connections:
+- database: db
+  dsn: db
+  driver: fake
+  queries:
+  - name: foo
+    help: foo
+    sql: select 1 as v from dual
+    metrics: [v]
+  - name: bar
+    help: bar
+    sql: select 1 as v from dual
+    metrics:
+    - column: v
+      name: oracledb_custom_foo
+`,
+			want: []string{"14: metric oracledb_custom_foo has labels [database dbinstance rownum], but [metric database dbinstance rownum]"},
+		},
+		{
+			name: "problems of a query at their key",
+			config: `connections:
+- database: db
+  dsn: db
+  driver: fake
+  queries:
+  - name: q
+    sql: select 1 as v from dual
+    labels: [ok, "not-valid"]
+    metrics:
+    - column: v
+      name: q_value
+      type: summary
+`,
+			want: []string{
+				"6: query q: help is missing",
+				`8: query q: invalid label name "not-valid"`,
+				`12: query q: metric q_value: unknown type "summary"`,
+			},
+		},
+		{
+			name: "missing paths are warnings",
+			config: `connections:
+- database: db
+  dsn: db
+  driver: fake
+  tns_admin: /nonexistent/tns_admin
+  alertlog:
+  - file: /nonexistent/alert.log
+`,
+		},
+		{
+			name:        "missing paths fail the check",
+			configCheck: true,
+			config: `connections:
+- database: db
+  dsn: db
+  driver: fake
+  tns_admin: /nonexistent/tns_admin
+  alertlog:
+  - file: /nonexistent/alert.log
+`,
+			want: []string{"5: tns_admin: stat /nonexistent/tns_admin", "7: alertlog: stat /nonexistent/alert.log"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			*configCheck = tt.configCheck
+			if err := ioutil.WriteFile(file, []byte(tt.config), 0600); err != nil {
+				t.Fatal(err)
+			}
+			_, err := readConfig(file)
+			if len(tt.want) == 0 {
+				if err != nil {
+					t.Fatalf("unexpected problems:\n%v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("no problems, want %v", tt.want)
+			}
+			problems, ok := err.(configErrors)
+			if !ok {
+				t.Fatalf("error %v is not a configErrors", err)
+			}
+			if len(problems) != len(tt.want) {
+				t.Errorf("got %d problems, want %d:\n%v", len(problems), len(tt.want), err)
+			}
+			for _, want := range tt.want {
+				found := false
+				for _, p := range problems {
+					found = found || strings.HasPrefix(p, file+":"+want)
+				}
+				if !found {
+					t.Errorf("problem %s:%s not found in:\n%v", file, want, err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"flag"
+)
+
+var (
+	waitEventInclude regexpFlag
+	waitEventExclude regexpFlag
+	waitEventTop     = flag.Int("collector.waitevent.top", 20, "Only export the wait events with the most time waited since startup, 0 exports all.")
+)
+
+func init() {
+	flag.Var(&waitEventInclude, "collector.waitevent.include", "Regular expression of the wait events to export, all if not set.")
+	flag.Var(&waitEventExclude, "collector.waitevent.exclude", "Regular expression of the wait events not to export.")
+}
+
+// ScrapeWaitevent collects the waits of the non-idle wait events from
+// v$system_event, the events with the most time waited first.
+func (e *Exporter) ScrapeWaitevent(ctx context.Context, config *Config) error {
+	db := config.db
+
+	rows, err := db.QueryContext(ctx, `select event, wait_class, total_waits, total_timeouts, time_waited_micro
+                                 from v$system_event
+                                 where wait_class != 'Idle'
+                                 order by time_waited_micro desc`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	exported := 0
+	for rows.Next() {
+		var event string
+		var class string
+		var waits float64
+		var timeouts float64
+		var micros float64
+		if err := rows.Scan(&event, &class, &waits, &timeouts, &micros); err != nil {
+			return err
+		}
+		if (waitEventInclude.Regexp != nil && !waitEventInclude.matches(event)) || waitEventExclude.matches(event) {
+			continue
+		}
+		if *waitEventTop > 0 && exported >= *waitEventTop {
+			break
+		}
+		exported++
+		e.waitEventWaits.WithLabelValues(config.Database, config.Instance, event, class).Set(waits)
+		e.waitEventTimeouts.WithLabelValues(config.Database, config.Instance, event, class).Set(timeouts)
+		e.waitEventTime.WithLabelValues(config.Database, config.Instance, event, class).Set(micros / 1e6)
+	}
+	return rows.Err()
+}
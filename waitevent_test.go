@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"database/sql/driver"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestScrapeWaitevent(t *testing.T) {
+	defer func(include, exclude regexpFlag, top int) {
+		waitEventInclude, waitEventExclude, *waitEventTop = include, exclude, top
+	}(waitEventInclude, waitEventExclude, *waitEventTop)
+
+	// as v$system_event returns them, the most time waited first
+	rows := [][]driver.Value{
+		{"db file sequential read", "User I/O", int64(400), int64(0), int64(4000000)},
+		{"log file sync", "Commit", int64(300), int64(1), int64(3000000)},
+		{"db file scattered read", "User I/O", int64(200), int64(0), int64(2000000)},
+		{"enq: TX - row lock contention", "Application", int64(100), int64(10), int64(1000000)},
+	}
+	tests := []struct {
+		name    string
+		top     int
+		include string
+		exclude string
+		// seconds waited by event
+		want map[string]float64
+	}{
+		{
+			name: "all",
+			want: map[string]float64{"db file sequential read": 4, "log file sync": 3, "db file scattered read": 2, "enq: TX - row lock contention": 1},
+		},
+		{
+			name: "top events by time waited",
+			top:  2,
+			want: map[string]float64{"db file sequential read": 4, "log file sync": 3},
+		},
+		{
+			name:    "included events",
+			include: "db file .*",
+			want:    map[string]float64{"db file sequential read": 4, "db file scattered read": 2},
+		},
+		{
+			name:    "excluded events",
+			exclude: "db file .*|log file sync",
+			want:    map[string]float64{"enq: TX - row lock contention": 1},
+		},
+		{
+			name:    "top of the filtered events",
+			top:     1,
+			exclude: "db file sequential read",
+			want:    map[string]float64{"log file sync": 3},
+		},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			*waitEventTop = tt.top
+			waitEventInclude, waitEventExclude = regexpFlag{}, regexpFlag{}
+			if tt.include != "" {
+				waitEventInclude.Set(tt.include)
+			}
+			if tt.exclude != "" {
+				waitEventExclude.Set(tt.exclude)
+			}
+			name := "waitevent_" + string(rune('a'+i))
+			db := &fakeDB{results: upResults(fakeResult{match: "from v$system_event",
+				columns: []string{"EVENT", "WAIT_CLASS", "TOTAL_WAITS", "TOTAL_TIMEOUTS", "TIME_WAITED_MICRO"}, rows: rows})}
+			config := addFakeDB(name, db)
+			defer dropPool(config)
+			var err error
+			if config.db, err = openPool(config); err != nil {
+				t.Fatal(err)
+			}
+
+			e := NewExporter()
+			if err := e.ScrapeWaitevent(context.Background(), config); err != nil {
+				t.Fatalf("scrape failed: %v", err)
+			}
+			// the top events are the first rows, the database orders them
+			for _, q := range db.queries {
+				if strings.Contains(q, "from v$system_event") && !strings.Contains(q, "order by time_waited_micro desc") {
+					t.Errorf("query %q doesn't order the events by time waited", q)
+				}
+			}
+			if got := testutil.CollectAndCount(e.waitEventTime); got != len(tt.want) {
+				t.Errorf("exported %d events, want %d", got, len(tt.want))
+			}
+			for _, r := range rows {
+				event, class := r[0].(string), r[1].(string)
+				want, ok := tt.want[event]
+				if !ok {
+					continue
+				}
+				if got := counterValueOf(e.waitEventTime, name, name, event, class); got != want {
+					t.Errorf("%s: time waited %v, want %v", event, got, want)
+				}
+				if got := counterValueOf(e.waitEventWaits, name, name, event, class); got != float64(r[2].(int64)) {
+					t.Errorf("%s: waits %v, want %v", event, got, r[2])
+				}
+			}
+		})
+	}
+}
+
+// counterValueOf returns the value of the counter with the label values.
+func counterValueOf(v *counterVec, labelValues ...string) float64 {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.values[strings.Join(labelValues, "\xff")].value
+}
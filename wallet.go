@@ -0,0 +1,211 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+// errWallet marks errors of the wallet lookup before connecting.
+var errWallet = errors.New("wallet lookup failed")
+
+// TNS_ADMIN is process wide and read by the client during the logon. The
+// logons of connections with their own tns_admin change it exclusively, all
+// other logons of the same client share the lock, so none of them resolves
+// its alias with the directory of another connection.
+var tnsAdminLock rwLock
+
+// rwLock is a readers-writer lock whose waiting ends with a context. A waiting
+// writer blocks new readers, so the logons of a busy exporter can't starve it.
+type rwLock struct {
+	mu             sync.Mutex
+	readers        int
+	writer         bool
+	waitingWriters int
+	// closed whenever the state changes, waiters check again
+	changed chan struct{}
+}
+
+// lock takes the lock exclusively or shared, or returns the error of the
+// context if it ends first.
+func (l *rwLock) lock(ctx context.Context, exclusive bool) error {
+	l.mu.Lock()
+	if exclusive {
+		l.waitingWriters++
+	}
+	for {
+		free := !l.writer && (exclusive && l.readers == 0 || !exclusive && l.waitingWriters == 0)
+		if free {
+			if exclusive {
+				l.waitingWriters--
+				l.writer = true
+			} else {
+				l.readers++
+			}
+			l.mu.Unlock()
+			return nil
+		}
+		if l.changed == nil {
+			l.changed = make(chan struct{})
+		}
+		changed := l.changed
+		l.mu.Unlock()
+
+		select {
+		case <-changed:
+			l.mu.Lock()
+		case <-ctx.Done():
+			l.mu.Lock()
+			if exclusive {
+				l.waitingWriters--
+				l.notify()
+			}
+			l.mu.Unlock()
+			return ctx.Err()
+		}
+	}
+}
+
+func (l *rwLock) unlock(exclusive bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if exclusive {
+		l.writer = false
+	} else {
+		l.readers--
+	}
+	l.notify()
+}
+
+// notify wakes up the waiters, l.mu is held.
+func (l *rwLock) notify() {
+	if l.changed != nil {
+		close(l.changed)
+		l.changed = nil
+	}
+}
+
+// tnsAdminConnector opens the connections of a client which reads TNS_ADMIN
+// from the environment, see dbDriver.tnsAdminEnv. With a tnsAdmin it points
+// TNS_ADMIN to that directory, which holds the sqlnet.ora, tnsnames.ora and
+// wallet of the connection.
+type tnsAdminConnector struct {
+	connector driver.Connector
+	tnsAdmin  string
+}
+
+// newTnsAdminConnector returns the connector of the driver registered with
+// database/sql as sqlName, wrapped to take tnsAdminLock.
+func newTnsAdminConnector(sqlName, dsn, tnsAdmin string) (*tnsAdminConnector, error) {
+	db, err := sql.Open(sqlName, "")
+	if err != nil {
+		return nil, err
+	}
+	drv := db.Driver()
+	db.Close()
+
+	var connector driver.Connector = dsnConnector{driver: drv, dsn: dsn}
+	if d, ok := drv.(driver.DriverContext); ok {
+		if connector, err = d.OpenConnector(dsn); err != nil {
+			return nil, err
+		}
+	}
+	return &tnsAdminConnector{connector: connector, tnsAdmin: tnsAdmin}, nil
+}
+
+func (c *tnsAdminConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	exclusive := c.tnsAdmin != ""
+	if exclusive {
+		if _, err := os.Stat(c.tnsAdmin); err != nil {
+			return nil, fmt.Errorf("%w: %v", errWallet, err)
+		}
+	}
+	if err := tnsAdminLock.lock(ctx, exclusive); err != nil {
+		return nil, err
+	}
+
+	type result struct {
+		conn driver.Conn
+		err  error
+	}
+	done := make(chan result, 1)
+	// the lock is held, and TNS_ADMIN set, until the logon returns, also
+	// after the context ended
+	go func() {
+		var restore func()
+		if exclusive {
+			old, ok := os.LookupEnv("TNS_ADMIN")
+			os.Setenv("TNS_ADMIN", c.tnsAdmin)
+			restore = func() {
+				if ok {
+					os.Setenv("TNS_ADMIN", old)
+				} else {
+					os.Unsetenv("TNS_ADMIN")
+				}
+			}
+		}
+		conn, err := c.connector.Connect(ctx)
+		if restore != nil {
+			restore()
+		}
+		tnsAdminLock.unlock(exclusive)
+		done <- result{conn, err}
+	}()
+
+	select {
+	case r := <-done:
+		return r.conn, r.err
+	case <-ctx.Done():
+		// a logon which still succeeds is not used
+		go func() {
+			if r := <-done; r.conn != nil {
+				r.conn.Close()
+			}
+		}()
+		return nil, ctx.Err()
+	}
+}
+
+func (c *tnsAdminConnector) Driver() driver.Driver {
+	return c.connector.Driver()
+}
+
+// dsnConnector opens the connections of a driver without a connector of its own.
+type dsnConnector struct {
+	driver driver.Driver
+	dsn    string
+}
+
+func (c dsnConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	return c.driver.Open(c.dsn)
+}
+
+func (c dsnConnector) Driver() driver.Driver {
+	return c.driver
+}
+
+// connectErrorReason classifies why a connection failed, for the connect_error metric.
+func connectErrorReason(err error) string {
+	msg := err.Error()
+	switch {
+	case errors.Is(err, errWallet),
+		strings.Contains(msg, "ORA-12578"), // TNS:wallet open failed
+		strings.Contains(msg, "ORA-28759"), // failure to open file
+		strings.Contains(msg, "ORA-01005"): // null password given, no credential for the alias in the wallet
+		return "wallet"
+	case strings.Contains(msg, "ORA-12154"): // TNS:could not resolve the connect identifier
+		return "tns"
+	case strings.Contains(msg, "ORA-01017"), // invalid username/password
+		strings.Contains(msg, "ORA-28000"), // account is locked
+		strings.Contains(msg, "ORA-28001"): // password has expired
+		return "auth"
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(err, context.Canceled):
+		return "timeout"
+	}
+	return "other"
+}
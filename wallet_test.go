@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestConnectErrorReason(t *testing.T) {
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{fmt.Errorf("%w: stat /nonexistent: no such file or directory", errWallet), "wallet"},
+		{errors.New("ORA-12578: TNS:wallet open failed"), "wallet"},
+		{errors.New("ORA-28759: failure to open file"), "wallet"},
+		{errors.New("ORA-01005: null password given; logon denied"), "wallet"},
+		{errors.New("ORA-12154: TNS:could not resolve the connect identifier specified"), "tns"},
+		{errors.New("ORA-01017: invalid username/password; logon denied"), "auth"},
+		{errors.New("ORA-28000: the account is locked"), "auth"},
+		{errors.New("ORA-28001: the password has expired"), "auth"},
+		{context.DeadlineExceeded, "timeout"},
+		{fmt.Errorf("ping: %w", context.Canceled), "timeout"},
+		{errors.New("ORA-12541: TNS:no listener"), "other"},
+	}
+	for _, tt := range tests {
+		if got := connectErrorReason(tt.err); got != tt.want {
+			t.Errorf("connectErrorReason(%q) = %s, want %s", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestTnsAdminConnectorDeadline(t *testing.T) {
+	hang := make(chan struct{})
+	defer close(hang)
+	addFakeDB("wallet_hang", &fakeDB{hang: hang})
+	addFakeDB("wallet_up", &fakeDB{results: upResults()})
+
+	hung, err := newTnsAdminConnector("fakedb", "wallet_hang", os.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	go hung.Connect(context.Background())
+	// wait until the hanging logon holds the lock
+	for locked := false; !locked; time.Sleep(time.Millisecond) {
+		tnsAdminLock.mu.Lock()
+		locked = tnsAdminLock.writer
+		tnsAdminLock.mu.Unlock()
+	}
+
+	// other logons of the client wait, with or without tns_admin, but only
+	// until their deadline
+	for _, tnsAdmin := range []string{os.TempDir(), ""} {
+		up, err := newTnsAdminConnector("fakedb", "wallet_up", tnsAdmin)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		begun := time.Now()
+		if _, err := up.Connect(ctx); !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("tns_admin %q: connect behind a hanging logon: %v, want the deadline", tnsAdmin, err)
+		}
+		if d := time.Since(begun); d > time.Second {
+			t.Errorf("tns_admin %q: connect behind a hanging logon took %v, the deadline was 100ms", tnsAdmin, d)
+		}
+		cancel()
+	}
+}
+
+func TestRWLock(t *testing.T) {
+	var l rwLock
+	ctx := context.Background()
+	short := func() (context.Context, context.CancelFunc) {
+		return context.WithTimeout(ctx, 50*time.Millisecond)
+	}
+
+	// readers share the lock, a writer waits for them
+	if err := l.lock(ctx, false); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.lock(ctx, false); err != nil {
+		t.Fatal(err)
+	}
+	writer := make(chan error, 1)
+	go func() { writer <- l.lock(ctx, true) }()
+	for waiting := false; !waiting; time.Sleep(time.Millisecond) {
+		l.mu.Lock()
+		waiting = l.waitingWriters == 1
+		l.mu.Unlock()
+	}
+
+	// the waiting writer blocks new readers
+	c, cancel := short()
+	if err := l.lock(c, false); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("reader behind a waiting writer: %v, want the deadline", err)
+	}
+	cancel()
+
+	l.unlock(false)
+	l.unlock(false)
+	if err := <-writer; err != nil {
+		t.Fatalf("writer: %v", err)
+	}
+	c, cancel = short()
+	if err := l.lock(c, false); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("reader behind a writer: %v, want the deadline", err)
+	}
+	cancel()
+	l.unlock(true)
+
+	// a writer which gives up doesn't block the readers
+	if err := l.lock(ctx, false); err != nil {
+		t.Fatal(err)
+	}
+	c, cancel = short()
+	if err := l.lock(c, true); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("writer behind a reader: %v, want the deadline", err)
+	}
+	cancel()
+	c, cancel = short()
+	defer cancel()
+	if err := l.lock(c, false); err != nil {
+		t.Errorf("reader after a writer gave up: %v", err)
+	}
+}